@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	ctrl "github.com/ChristopherLeo15/opentable/apikey/internal/controller/apikey"
+	httph "github.com/ChristopherLeo15/opentable/apikey/internal/handler/http"
+	repo "github.com/ChristopherLeo15/opentable/apikey/internal/repository/memory"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/ratelimit"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+func main() {
+	var portFlag = flag.Int("port", 8084, "port to listen on")
+	flag.Parse()
+
+	port := *portFlag
+	if env := os.Getenv("PORT"); env != "" {
+		if p, err := strconv.Atoi(env); err == nil {
+			port = p
+		}
+	}
+	serviceName := getenvDefault("SERVICE_NAME", "apikey")
+
+	// Tracing: export via OTLP when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName)
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+
+	// Auth: key management needs an admin JWT unless AUTH_DISABLED=true (local runs only)
+	var mw []func(http.Handler) http.Handler
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("auth disabled: every request runs as admin")
+		mw = append(mw, auth.Static(auth.Principal{Subject: "dev", Roles: []string{"admin"}}))
+	} else {
+		verifier, err := auth.VerifierFromEnv()
+		if err != nil {
+			log.Fatalf("auth init: %v", err)
+		}
+		mw = append(mw, verifier.Middleware)
+	}
+
+	// Rate limiting runs after auth so clients are keyed by principal, not only IP.
+	// The other services call /apikeys/check on every partner request, so it
+	// gets a much larger bucket, as do the services themselves (keyed by their
+	// service token). RATE_LIMIT_DEFAULT / RATE_LIMIT_ROUTES / RATE_LIMIT_INTERNAL override.
+	rl, err := ratelimit.ConfigFromEnv(ratelimit.Config{
+		Default:  ratelimit.Limit{Rate: 20, Burst: 40},
+		Routes:   map[string]ratelimit.Limit{"GET /apikeys/check": {Rate: 1000, Burst: 2000}},
+		Internal: ratelimit.Limit{Rate: 1000, Burst: 2000},
+	})
+	if err != nil {
+		log.Fatalf("rate limit config: %v", err)
+	}
+	mw = append(mw, ratelimit.Middleware(rl, ratelimit.NewMemory()))
+
+	r := repo.New()
+	c := ctrl.New(r)
+	metrics.GaugeFunc("apikey_records", "Number of API keys issued.", func() float64 { return float64(r.Count()) })
+	h := httph.New(c)
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           tracing.Handler(h.Router(mw...), serviceName),
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	// Register in Consul
+	consulAddr := getenvDefault("CONSUL_HTTP_ADDR", "http://consul:8500")
+	serviceID := fmt.Sprintf("%s-%d", serviceName, port)
+	if err := registerWithConsul(consulAddr, serviceID, serviceName, "apikey", port, "/healthz"); err != nil {
+		log.Printf("consul register failed: %v", err)
+	}
+
+	go func() {
+		log.Printf("%s service listening on :%d", serviceName, port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server error: %v", err)
+		}
+	}()
+
+	// Clean exit and deregister from Consul
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = deregisterFromConsul(consulAddr, serviceID)
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("clean exit error: %v", err)
+	} else {
+		log.Println("server stopped cleanly")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("tracing shutdown error: %v", err)
+	}
+}
+
+func getenvDefault(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
+
+func registerWithConsul(consul, id, name, dnsName string, port int, healthPath string) error {
+	payload := map[string]any{
+		"ID":   id,
+		"Name": name,
+		// Consul talks to our service at this DNS name and port
+		"Address": dnsName,
+		"Port":    port,
+		"Check": map[string]any{
+			// Consul will call /healthz every 10s; remove after 1m if failing
+			"HTTP":                           fmt.Sprintf("http://%s:%d%s", dnsName, port, healthPath),
+			"Interval":                       "10s",
+			"DeregisterCriticalServiceAfter": "1m",
+		},
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(http.MethodPut, consul+"/v1/agent/service/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("consul register: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func deregisterFromConsul(consul, id string) error {
+	req, _ := http.NewRequest(http.MethodPut, consul+"/v1/agent/service/deregister/"+id, nil)
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
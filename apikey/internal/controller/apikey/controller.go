@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	m "github.com/ChristopherLeo15/opentable/apikey/internal/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+)
+
+// Interface for storing keys and their daily usage.
+type Repository interface {
+	Create(k m.Key) error
+	GetByID(id string) (m.Key, error)
+	All() []m.Key
+	Revoke(id string, at time.Time) error
+	Consume(id, day string, limit int) (int, bool)
+}
+
+type Controller struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func New(repo Repository) *Controller {
+	return &Controller{repo: repo, now: time.Now}
+}
+
+// Issue creates a key and returns it with its plaintext, which is never
+// shown again.
+func (c *Controller) Issue(ctx context.Context, in m.Key) (m.Key, string, error) {
+	if _, err := authz.Authorize(ctx, authz.APIKeyManage); err != nil {
+		return m.Key{}, "", err
+	}
+	if err := in.Validate(); err != nil {
+		return m.Key{}, "", err
+	}
+	id, plaintext, err := apikey.Generate()
+	if err != nil {
+		return m.Key{}, "", err
+	}
+	k := m.Key{
+		ID:         id,
+		Name:       in.Name,
+		Scopes:     in.Scopes,
+		DailyQuota: in.DailyQuota,
+		Hash:       apikey.Hash(plaintext),
+		CreatedAt:  c.now().UTC(),
+	}
+	if err := c.repo.Create(k); err != nil {
+		return m.Key{}, "", err
+	}
+	return k, plaintext, nil
+}
+
+func (c *Controller) List(ctx context.Context) ([]m.Key, error) {
+	if _, err := authz.Authorize(ctx, authz.APIKeyManage); err != nil {
+		return nil, err
+	}
+	return c.repo.All(), nil
+}
+
+func (c *Controller) Revoke(ctx context.Context, id string) error {
+	if _, err := authz.Authorize(ctx, authz.APIKeyManage); err != nil {
+		return err
+	}
+	return c.repo.Revoke(id, c.now().UTC())
+}
+
+// Check authenticates plaintext for scope and consumes one request from the
+// key's quota for the current UTC day.
+func (c *Controller) Check(ctx context.Context, plaintext, scope string) (apikey.CheckResult, error) {
+	id, ok := apikey.ParseID(plaintext)
+	if !ok {
+		return apikey.CheckResult{}, apperr.Unauthenticated("invalid api key")
+	}
+	k, err := c.repo.GetByID(id)
+	if err != nil || !apikey.Matches(plaintext, k.Hash) {
+		return apikey.CheckResult{}, apperr.Unauthenticated("invalid api key")
+	}
+	if k.RevokedAt != nil {
+		return apikey.CheckResult{}, apperr.Unauthenticated("api key revoked")
+	}
+	if !slices.Contains(k.Scopes, scope) {
+		return apikey.CheckResult{}, apperr.Forbidden("api key lacks scope %s", scope)
+	}
+
+	now := c.now().UTC()
+	day := now.Format(time.DateOnly)
+	used, ok := c.repo.Consume(k.ID, day, k.DailyQuota)
+	if !ok {
+		return apikey.CheckResult{}, apperr.RateLimited("daily quota of %d requests exhausted", k.DailyQuota)
+	}
+	y, mo, d := now.Date()
+	return apikey.CheckResult{
+		KeyID:      k.ID,
+		Name:       k.Name,
+		Scopes:     k.Scopes,
+		DailyQuota: k.DailyQuota,
+		Remaining:  k.DailyQuota - used,
+		ResetsAt:   time.Date(y, mo, d+1, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	ctrl "github.com/ChristopherLeo15/opentable/apikey/internal/controller/apikey"
+	m "github.com/ChristopherLeo15/opentable/apikey/internal/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
+)
+
+type Handler struct {
+	c *ctrl.Controller
+}
+
+func New(c *ctrl.Controller) *Handler { return &Handler{c: c} }
+
+// Router wires the routes; mw (auth, rate limiting, ...) wraps every route.
+func (h *Handler) Router(mw ...func(http.Handler) http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apikeys", h.handleKeys)     // GET list, POST issue, DELETE ?id= revoke
+	mux.HandleFunc("/apikeys/check", h.checkKey) // GET with X-API-Key, ?scope= (called by the other services)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.Handle("/metrics", metrics.Handler())
+	return metrics.Middleware(mux, mw...)
+}
+
+func (h *Handler) handleKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listKeys(w, r)
+	case http.MethodPost:
+		h.issueKey(w, r)
+	case http.MethodDelete:
+		h.revokeKey(w, r)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.c.List(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler) issueKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var in m.Key
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	k, plaintext, err := h.c.Issue(r.Context(), in)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	type response struct {
+		m.Key
+		// Shown once; only its hash is stored
+		Plaintext string `json:"key"`
+	}
+	writeJSON(w, http.StatusCreated, response{Key: k, Plaintext: plaintext})
+}
+
+func (h *Handler) revokeKey(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		problem.Error(w, r, http.StatusBadRequest, "id is required")
+		return
+	}
+	if err := h.c.Revoke(r.Context(), id); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) checkKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		problem.Error(w, r, http.StatusBadRequest, "scope is required")
+		return
+	}
+	res, err := h.c.Check(r.Context(), r.Header.Get(apikey.Header), scope)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// ----- Support function -----
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
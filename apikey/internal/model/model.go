@@ -0,0 +1,42 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Key is a partner API key. Only the hash of the secret is kept.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	DailyQuota int        `json:"daily_quota"`
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k Key) Validate() error {
+	var fields []apperr.FieldError
+	if k.Name == "" {
+		fields = append(fields, apperr.FieldError{Field: "name", Message: "is required"})
+	}
+	if len(k.Scopes) == 0 {
+		fields = append(fields, apperr.FieldError{Field: "scopes", Message: "at least one scope is required"})
+	}
+	for _, s := range k.Scopes {
+		if !slices.Contains(apikey.Scopes, s) {
+			fields = append(fields, apperr.FieldError{Field: "scopes", Message: "unknown scope " + s})
+		}
+	}
+	if k.DailyQuota <= 0 {
+		fields = append(fields, apperr.FieldError{Field: "daily_quota", Message: "must be positive"})
+	}
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	m "github.com/ChristopherLeo15/opentable/apikey/internal/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+var ErrNotFound = apperr.NotFound("api key not found")
+
+type usage struct {
+	day  string
+	used int
+}
+
+type Repo struct {
+	// Mutex for safe concurrent access
+	mu    sync.RWMutex
+	keys  map[string]m.Key
+	usage map[string]usage
+}
+
+func New() *Repo {
+	return &Repo{keys: make(map[string]m.Key), usage: make(map[string]usage)}
+}
+
+func (r *Repo) Create(k m.Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[k.ID]; ok {
+		return apperr.Conflict("api key id already exists")
+	}
+	r.keys[k.ID] = k
+	return nil
+}
+
+func (r *Repo) GetByID(id string) (m.Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.keys[id]
+	if !ok {
+		return m.Key{}, ErrNotFound
+	}
+	return k, nil
+}
+
+// All returns keys oldest first.
+func (r *Repo) All() []m.Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]m.Key, 0, len(r.keys))
+	for _, k := range r.keys {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (r *Repo) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.keys)
+}
+
+func (r *Repo) Revoke(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = &at
+		r.keys[id] = k
+	}
+	return nil
+}
+
+// Consume counts one request against id's quota for day. It returns the
+// number of requests used so far and false once limit is exceeded.
+func (r *Repo) Consume(id, day string, limit int) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.usage[id]
+	if u.day != day {
+		u = usage{day: day}
+	}
+	if u.used >= limit {
+		return u.used, false
+	}
+	u.used++
+	r.usage[id] = u
+	return u.used, true
+}
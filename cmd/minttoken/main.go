@@ -0,0 +1,82 @@
+// Command minttoken signs JWTs for local development against the services'
+// auth middleware.
+//
+//	go run ./cmd/minttoken -sub alice -roles admin -hs256-secret-file dev/jwt-hs256.secret
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+)
+
+func main() {
+	var (
+		sub        = flag.String("sub", "dev-user", "subject (user id)")
+		roles      = flag.String("roles", "", "comma-separated roles")
+		ttl        = flag.Duration("ttl", time.Hour, "token lifetime")
+		issuer     = flag.String("iss", "", "issuer claim")
+		audience   = flag.String("aud", "", "audience claim")
+		kid        = flag.String("kid", "", "key id header (must match a JWKS entry)")
+		hmacFile   = flag.String("hs256-secret-file", "", "sign with HS256 using this secret file")
+		rsaKeyFile = flag.String("rs256-private-key-file", "", "sign with RS256 using this PEM private key")
+	)
+	flag.Parse()
+
+	now := time.Now()
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   *sub,
+			Issuer:    *issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(*ttl)),
+		},
+	}
+	if *audience != "" {
+		claims.Audience = jwt.ClaimStrings{*audience}
+	}
+	if *roles != "" {
+		claims.Roles = strings.Split(*roles, ",")
+	}
+
+	var (
+		token *jwt.Token
+		key   any
+	)
+	switch {
+	case *hmacFile != "":
+		secret, err := os.ReadFile(*hmacFile)
+		if err != nil {
+			log.Fatalf("read secret: %v", err)
+		}
+		token, key = jwt.NewWithClaims(jwt.SigningMethodHS256, claims), secret
+	case *rsaKeyFile != "":
+		pem, err := os.ReadFile(*rsaKeyFile)
+		if err != nil {
+			log.Fatalf("read private key: %v", err)
+		}
+		pk, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			log.Fatalf("parse private key: %v", err)
+		}
+		token, key = jwt.NewWithClaims(jwt.SigningMethodRS256, claims), pk
+	default:
+		log.Fatal("one of -hs256-secret-file or -rs256-private-key-file is required")
+	}
+	if *kid != "" {
+		token.Header["kid"] = *kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		log.Fatalf("sign: %v", err)
+	}
+	fmt.Println(signed)
+}
@@ -13,10 +13,21 @@ import (
 	"strconv"
 	"syscall"
 	"time"
+	// Restaurant timezones must resolve even where the image has no zoneinfo
+	_ "time/tzdata"
 
 	ctrl "github.com/ChristopherLeo15/opentable/metadata/internal/controller/metadata"
+	geocode "github.com/ChristopherLeo15/opentable/metadata/internal/gateway/geocode/local"
+	ratinggw "github.com/ChristopherLeo15/opentable/metadata/internal/gateway/rating/http"
 	httph "github.com/ChristopherLeo15/opentable/metadata/internal/handler/http"
 	repo "github.com/ChristopherLeo15/opentable/metadata/internal/repository/memory"
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/idempotency"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/ratelimit"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
 )
 
 func main() {
@@ -29,21 +40,92 @@ func main() {
 			port = p
 		}
 	}
+	serviceName := getenvDefault("SERVICE_NAME", "metadata")
+
+	// Tracing: export via OTLP when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName)
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+
+	// Calls to the other services carry a short-lived service token
+	if err := auth.InitService(serviceName); err != nil {
+		log.Fatalf("service token init: %v", err)
+	}
+
+	// Rate limits; other services get their own, larger quota per caller.
+	// RATE_LIMIT_DEFAULT / RATE_LIMIT_ROUTES / RATE_LIMIT_INTERNAL / RATE_LIMIT_PREAUTH
+	// override these defaults.
+	rl, err := ratelimit.ConfigFromEnv(ratelimit.Config{
+		Default:  ratelimit.Limit{Rate: 20, Burst: 40},
+		Internal: ratelimit.Limit{Rate: 500, Burst: 1000},
+		PreAuth:  ratelimit.Limit{Rate: 20, Burst: 40},
+		Routes:   map[string]ratelimit.Limit{},
+	})
+	if err != nil {
+		log.Fatalf("rate limit config: %v", err)
+	}
+	limits := ratelimit.NewMemory()
+
+	// Partners read with an X-API-Key, checked (and metered) by the apikey service;
+	// the same routes stay open to anonymous readers. Requests with a key are limited
+	// per IP first, so junk keys can't flood the apikey service.
+	mw := []func(http.Handler) http.Handler{
+		ratelimit.PreAuth(rl, apikey.Header, limits),
+		apikey.NewClient().Middleware(map[string]string{
+			"GET /metadata":        apikey.ScopeMetadataRead,
+			"GET /metadata/search": apikey.ScopeMetadataRead,
+			"GET /metadata/nearby": apikey.ScopeMetadataRead,
+		}),
+	}
+
+	// Auth: writes need a valid JWT unless AUTH_DISABLED=true (local runs only)
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("auth disabled: every request runs as admin")
+		mw = append(mw, auth.Static(auth.Principal{Subject: "dev", Roles: []string{"admin"}}))
+	} else {
+		verifier, err := auth.VerifierFromEnv()
+		if err != nil {
+			log.Fatalf("auth init: %v", err)
+		}
+		mw = append(mw, verifier.Middleware)
+	}
+
+	// Rate limiting runs after auth so clients are keyed by principal, not only IP
+	mw = append(mw, ratelimit.Middleware(rl, limits))
+
+	// Retried POSTs with the same Idempotency-Key replay the first response
+	mw = append(mw, idempotency.Middleware(idempotency.NewMemory(), idempotency.TTLFromEnv()))
+
+	// Background jobs stop on shutdown
+	bg, stopBg := context.WithCancel(context.Background())
+	defer stopBg()
+
+	// Review summaries boost search results; refreshed every RATING_REFRESH_INTERVAL
+	ratings := ratinggw.New()
+	go ratings.Run(bg, integrity.IntervalFromEnv("RATING_REFRESH_INTERVAL", 5*time.Minute))
+
+	// Addresses without coordinates are geocoded from GEOCODE_DATA_FILE
+	// (built-in dataset when unset)
+	geocoder, err := geocode.FromEnv("GEOCODE_DATA_FILE")
+	if err != nil {
+		log.Fatalf("geocoder: %v", err)
+	}
 
 	r := repo.New()
-	c := ctrl.New(r)
+	c := ctrl.New(r, ratings, geocoder)
+	metrics.GaugeFunc("metadata_records", "Number of metadata records stored.", func() float64 { return float64(r.Count()) })
 	h := httph.New(c)
 
 	srv := &http.Server{
 		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           h.Router(),
+		Handler:           tracing.Handler(h.Router(mw...), serviceName),
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      10 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
 
 	// Register in Consul
-	serviceName := getenvDefault("SERVICE_NAME", "metadata")
 	consulAddr := getenvDefault("CONSUL_HTTP_ADDR", "http://consul:8500")
 	serviceID := fmt.Sprintf("%s-%d", serviceName, port)
 	if err := registerWithConsul(consulAddr, serviceID, serviceName, "metadata", port, "/healthz"); err != nil {
@@ -61,6 +143,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
+	stopBg()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -71,6 +154,9 @@ func main() {
 	} else {
 		log.Println("server stopped cleanly")
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("tracing shutdown error: %v", err)
+	}
 }
 
 func getenvDefault(k, def string) string {
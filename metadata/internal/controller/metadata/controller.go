@@ -2,56 +2,210 @@ package metadata
 
 import (
 	"context"
-	"fmt"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/metadata/internal/geo"
+	"github.com/ChristopherLeo15/opentable/metadata/internal/search"
 	m "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
 )
 
+const tracerScope = "metadata/controller"
+
 type Repository interface {
 	GetAll() []m.Metadata
 	GetByID(id int) (m.Metadata, error)
-	Add(x m.Metadata)
+	ListByCity(city string) []m.Metadata
+	ListByCuisine(cuisine string) []m.Metadata
+	Add(x m.Metadata) (m.Metadata, error)
+	Update(x m.Metadata, pre etag.Precondition) (m.Metadata, error)
+}
+
+// Geocoder resolves an address to a location; nil (and no error) when
+// unknown.
+type Geocoder interface {
+	Geocode(ctx context.Context, address, city string) (*m.Location, error)
+}
+
+// Ratings supplies review summaries used to boost search results.
+type Ratings interface {
+	Lookup(metadataID int) (search.Rating, bool)
 }
 
 type Controller struct {
 	repo Repository
+	// Search and spatial indexes, kept in sync with every write
+	index    *search.Index
+	places   *geo.Index
+	ratings  Ratings
+	geocoder Geocoder
 }
 
-func New(repo Repository) *Controller {
-	return &Controller{repo: repo}
+// New indexes the repository's records. ratings may be nil, in which case
+// searches ignore the rating boost; geocoder may be nil, in which case only
+// client-supplied locations are stored.
+func New(repo Repository, ratings Ratings, geocoder Geocoder) *Controller {
+	c := &Controller{repo: repo, index: search.NewIndex(), places: geo.NewIndex(), ratings: ratings, geocoder: geocoder}
+	for _, x := range repo.GetAll() {
+		c.reindex(x)
+	}
+	return c
 }
 
-func (c *Controller) List(ctx context.Context) []m.Metadata {
-	return c.repo.GetAll()
+func (c *Controller) reindex(x m.Metadata) {
+	c.index.Put(x)
+	if l := x.Location; l != nil {
+		c.places.Put(x.ID, x.Version, l.Latitude, l.Longitude)
+	} else {
+		c.places.Remove(x.ID, x.Version)
+	}
+}
+
+// Filter narrows List; empty fields match everything.
+type Filter struct {
+	City        string
+	CuisineType string
+	// Only restaurants open at this instant, when set
+	OpenAt time.Time
+}
+
+func (c *Controller) List(ctx context.Context, f Filter) []m.Metadata {
+	out := c.list(ctx, f)
+	now := time.Now()
+	filtered := out[:0]
+	for _, x := range out {
+		if !f.OpenAt.IsZero() && (x.Hours == nil || !x.Hours.OpenAt(f.OpenAt)) {
+			continue
+		}
+		filtered = append(filtered, present(x, now))
+	}
+	return filtered
+}
+
+func (c *Controller) list(ctx context.Context, f Filter) []m.Metadata {
+	switch {
+	case f.City != "":
+		_, span := tracing.Start(ctx, tracerScope, "repository.ListByCity")
+		defer span.End()
+		out := c.repo.ListByCity(f.City)
+		if f.CuisineType == "" {
+			return out
+		}
+		// Narrow the (smaller) city bucket by cuisine
+		filtered := out[:0]
+		for _, x := range out {
+			if strings.EqualFold(x.CuisineType, f.CuisineType) {
+				filtered = append(filtered, x)
+			}
+		}
+		return filtered
+	case f.CuisineType != "":
+		_, span := tracing.Start(ctx, tracerScope, "repository.ListByCuisine")
+		defer span.End()
+		return c.repo.ListByCuisine(f.CuisineType)
+	default:
+		_, span := tracing.Start(ctx, tracerScope, "repository.GetAll")
+		defer span.End()
+		return c.repo.GetAll()
+	}
 }
 
 func (c *Controller) GetByID(ctx context.Context, id int) (m.Metadata, error) {
 	if id <= 0 {
-		return m.Metadata{}, fmt.Errorf("id must be positive")
+		return m.Metadata{}, apperr.Field("id", "must be positive")
 	}
-	return c.repo.GetByID(id)
+	_, span := tracing.Start(ctx, tracerScope, "repository.GetByID", attribute.Int("metadata.id", id))
+	x, err := c.repo.GetByID(id)
+	tracing.End(span, err)
+	return present(x, time.Now()), err
 }
 
 func (c *Controller) Add(ctx context.Context, x m.Metadata) (m.Metadata, error) {
-	// Simple validation
-	if x.Name == "" {
-		return m.Metadata{}, fmt.Errorf("name is required")
+	if _, err := authz.Authorize(ctx, authz.MetadataCreate); err != nil {
+		return m.Metadata{}, err
 	}
-	if x.CuisineType == "" {
-		return m.Metadata{}, fmt.Errorf("cuisine_type is required")
+	x.Normalize()
+	if err := x.Validate(); err != nil {
+		return m.Metadata{}, err
 	}
 
-	// Auto-assign ID if not provided
-	if x.ID == 0 {
-		next := 1
-		for _, cur := range c.repo.GetAll() {
-			if cur.ID >= next {
-				next = cur.ID + 1
-			}
-		}
-		x.ID = next
+	if x.ID < 0 {
+		return m.Metadata{}, apperr.Field("id", "must be positive")
+	}
+	c.locate(ctx, &x)
+
+	// The repository assigns the ID when none is provided
+	_, span := tracing.Start(ctx, tracerScope, "repository.Add")
+	out, err := c.repo.Add(x)
+	span.SetAttributes(attribute.Int("metadata.id", out.ID))
+	tracing.End(span, err)
+	if err == nil {
+		c.reindex(out)
+	}
+	return present(out, time.Now()), err
+}
+
+// Update replaces the record with the given id; it runs the same
+// normalization and validation as Add. pre (from If-Match) must allow the
+// stored version, so concurrent editors don't overwrite each other.
+func (c *Controller) Update(ctx context.Context, id int, pre etag.Precondition, x m.Metadata) (m.Metadata, error) {
+	if _, err := authz.Authorize(ctx, authz.MetadataUpdate); err != nil {
+		return m.Metadata{}, err
+	}
+	if id <= 0 {
+		return m.Metadata{}, apperr.Field("id", "must be positive")
+	}
+	if x.ID != 0 && x.ID != id {
+		return m.Metadata{}, apperr.Field("id", "does not match the record being updated")
+	}
+	x.ID = id
+	x.Normalize()
+	if err := x.Validate(); err != nil {
+		return m.Metadata{}, err
 	}
+	c.locate(ctx, &x)
 
-	c.repo.Add(x)
-	return x, nil
-}
\ No newline at end of file
+	_, span := tracing.Start(ctx, tracerScope, "repository.Update", attribute.Int("metadata.id", id))
+	out, err := c.repo.Update(x, pre)
+	tracing.End(span, err)
+	if err == nil {
+		c.reindex(out)
+	}
+	return present(out, time.Now()), err
+}
+
+// present fills the computed fields of a record about to leave the
+// controller.
+func present(x m.Metadata, now time.Time) m.Metadata {
+	if x.Hours != nil {
+		open := x.Hours.OpenAt(now)
+		x.OpenNow = &open
+	}
+	return x
+}
+
+// locate geocodes x unless the client supplied its location. Geocoded
+// locations are refreshed on every write, since the address may have
+// changed; a failed lookup leaves the location unknown rather than failing
+// the write.
+func (c *Controller) locate(ctx context.Context, x *m.Metadata) {
+	if x.Location != nil && x.Location.Accuracy == "" {
+		return
+	}
+	x.Location = nil
+	if c.geocoder == nil {
+		return
+	}
+	_, span := tracing.Start(ctx, tracerScope, "geocoder.Geocode")
+	l, err := c.geocoder.Geocode(ctx, x.Address, x.City)
+	tracing.End(span, err)
+	if err == nil {
+		x.Location = l
+	}
+}
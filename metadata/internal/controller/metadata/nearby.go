@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	m "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// MaxNearbyRadiusKm bounds nearby queries.
+const MaxNearbyRadiusKm = 500
+
+type NearbyHit struct {
+	DistanceKm float64    `json:"distance_km"`
+	Metadata   m.Metadata `json:"metadata"`
+}
+
+// Nearby returns the records with a known location within radiusKm of
+// (lat, lng), nearest first.
+func (c *Controller) Nearby(ctx context.Context, lat, lng, radiusKm float64, limit int) ([]NearbyHit, error) {
+	var fields []apperr.FieldError
+	if !m.ValidLatitude(lat) {
+		fields = append(fields, apperr.FieldError{Field: "lat", Message: "must be between -90 and 90"})
+	}
+	if !m.ValidLongitude(lng) {
+		fields = append(fields, apperr.FieldError{Field: "lng", Message: "must be between -180 and 180"})
+	}
+	if !(radiusKm > 0 && radiusKm <= MaxNearbyRadiusKm) {
+		fields = append(fields, apperr.FieldError{Field: "radius_km", Message: "must be greater than 0 and at most 500"})
+	}
+	if len(fields) > 0 {
+		return nil, apperr.Validation(fields...)
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "places.Within")
+	hits := c.places.Within(lat, lng, radiusKm, limit)
+	span.SetAttributes(attribute.Int("nearby.hits", len(hits)))
+	span.End()
+
+	out := make([]NearbyHit, 0, len(hits))
+	now := time.Now()
+	for _, h := range hits {
+		x, err := c.repo.GetByID(h.ID)
+		if err != nil {
+			continue
+		}
+		// Metre precision is plenty
+		out = append(out, NearbyHit{DistanceKm: math.Round(h.DistanceKm*1000) / 1000, Metadata: present(x, now)})
+	}
+	return out, nil
+}
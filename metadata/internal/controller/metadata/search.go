@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/metadata/internal/search"
+	m "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// SearchRequest is a restaurant search. Filters are exact (case-insensitive)
+// facet values; BoostRating ranks well-reviewed restaurants higher.
+type SearchRequest struct {
+	Text        string
+	CuisineType string
+	City        string
+	PriceRange  string
+	BoostRating bool
+	Limit       int
+}
+
+type SearchHit struct {
+	Score    float64    `json:"score"`
+	Metadata m.Metadata `json:"metadata"`
+}
+
+type SearchResult struct {
+	Total   int                       `json:"total"`
+	Results []SearchHit               `json:"results"`
+	Facets  map[string]map[string]int `json:"facets"`
+}
+
+// Search matches Text against name, cuisine, city and address with typo
+// tolerance and returns the best records plus facet counts.
+func (c *Controller) Search(ctx context.Context, req SearchRequest) (SearchResult, error) {
+	if len(req.Text) > 200 {
+		return SearchResult{}, apperr.Field("q", "must be at most 200 characters")
+	}
+	price := req.PriceRange
+	if price != "" {
+		p, ok := m.NormalizePriceRange(price)
+		if !ok {
+			return SearchResult{}, apperr.Field("price_range", "must be one of $, $$, $$$, $$$$")
+		}
+		price = p
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	q := search.Query{
+		Text: req.Text,
+		Filters: map[string]string{
+			search.FacetCuisine: req.CuisineType,
+			search.FacetCity:    req.City,
+			search.FacetPrice:   price,
+		},
+		Limit: req.Limit,
+	}
+	if req.BoostRating && c.ratings != nil {
+		q.Boost = func(id int) float64 { return search.RatingBoost(c.ratings.Lookup(id)) }
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "index.Search")
+	res := c.index.Search(q)
+	span.SetAttributes(attribute.Int("search.total", res.Total))
+	span.End()
+
+	out := SearchResult{Total: res.Total, Results: make([]SearchHit, 0, len(res.Hits)), Facets: res.Facets}
+	now := time.Now()
+	for _, h := range res.Hits {
+		x, err := c.repo.GetByID(h.ID)
+		if err != nil {
+			continue
+		}
+		out.Results = append(out.Results, SearchHit{Score: h.Score, Metadata: present(x, now)})
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	m "github.com/ChristopherLeo15/opentable/metadata/model"
+)
+
+// Stand-in dataset until a geocoding provider is wired in.
+//
+//go:embed places.csv
+var places []byte
+
+// Geocoder resolves addresses from a fixed table: an exact street address
+// first, then the city centre.
+type Geocoder struct {
+	addresses map[string]m.Location
+	cities    map[string]m.Location
+}
+
+// New loads the built-in dataset.
+func New() *Geocoder {
+	g, err := parse(bytes.NewReader(places))
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// FromEnv loads the dataset named by env (same CSV layout as places.csv),
+// or the built-in one when env is unset.
+func FromEnv(env string) (*Geocoder, error) {
+	path := os.Getenv(env)
+	if path == "" {
+		return New(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+func parse(r io.Reader) (*Geocoder, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 4
+	g := &Geocoder{addresses: make(map[string]m.Location), cities: make(map[string]m.Location)}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return g, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+		lng, err2 := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64)
+		if err1 != nil || err2 != nil || !m.ValidLatitude(lat) || !m.ValidLongitude(lng) {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("geocode data line %d: bad coordinates", line)
+		}
+		city := key(rec[1])
+		if addr := key(rec[0]); addr != "" {
+			g.addresses[addr+"|"+city] = m.Location{Latitude: lat, Longitude: lng, Accuracy: m.AccuracyAddress}
+		} else {
+			g.cities[city] = m.Location{Latitude: lat, Longitude: lng, Accuracy: m.AccuracyCity}
+		}
+	}
+}
+
+// Geocode returns nil when neither the address nor the city is known.
+func (g *Geocoder) Geocode(_ context.Context, address, city string) (*m.Location, error) {
+	c := key(city)
+	if l, ok := g.addresses[key(address)+"|"+c]; ok {
+		return &l, nil
+	}
+	if l, ok := g.cities[c]; ok {
+		return &l, nil
+	}
+	return nil, nil
+}
+
+// key folds case and punctuation so "350 5th Ave." matches "350 5th ave".
+func key(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
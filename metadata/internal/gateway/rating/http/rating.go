@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/metadata/internal/search"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/discovery"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// Source keeps review summaries per metadata record for search ranking. It
+// refreshes in the background: restaurants map metadata ids to restaurant
+// ids, and the review service summarizes each restaurant. Searches never
+// wait on either service.
+type Source struct {
+	client      *http.Client
+	restaurants *discovery.Resolver
+	reviews     *discovery.Resolver
+
+	mu      sync.RWMutex
+	ratings map[int]search.Rating
+}
+
+func New() *Source {
+	tr := &http.Transport{
+		DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		IdleConnTimeout:       30 * time.Second,
+		MaxIdleConns:          10,
+	}
+	consul := &http.Client{Transport: tracing.Transport(tr)}
+	return &Source{
+		client:      &http.Client{Transport: auth.ServiceTransport(consul.Transport)},
+		restaurants: discovery.NewResolver("restaurant", consul),
+		reviews:     discovery.NewResolver("review", consul),
+		ratings:     make(map[int]search.Rating),
+	}
+}
+
+// Lookup returns the rating of a metadata record, if known.
+func (s *Source) Lookup(metadataID int) (search.Rating, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.ratings[metadataID]
+	return r, ok
+}
+
+// Run refreshes now and then every interval until ctx is done. Failed
+// refreshes keep the previous ratings.
+func (s *Source) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := s.refresh(ctx); err != nil {
+			log.Printf("ratings refresh: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// summaryBatch matches the review service's per-call limit.
+const summaryBatch = 100
+
+// refresh rebuilds the ratings from one restaurant listing and batched
+// review summaries. Records whose batch fails keep their previous rating,
+// so one bad call doesn't blank the boost for everything.
+func (s *Source) refresh(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "metadata/gateway/rating", "ratings.refresh")
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	var rests []struct {
+		ID         int `json:"id"`
+		MetadataID int `json:"metadata_id"`
+	}
+	if err = s.get(ctx, s.restaurants, "/restaurants", &rests); err != nil {
+		return err
+	}
+
+	// Several restaurants may share a metadata record; weight by count
+	sums := make(map[int]float64)
+	counts := make(map[int]int)
+	stale := make(map[int]bool)
+	var errs []error
+	for start := 0; start < len(rests); start += summaryBatch {
+		batch := rests[start:min(start+summaryBatch, len(rests))]
+		ids := make([]string, len(batch))
+		metadataIDs := make(map[int]int, len(batch))
+		for i, r := range batch {
+			ids[i] = strconv.Itoa(r.ID)
+			metadataIDs[r.ID] = r.MetadataID
+		}
+		var out []struct {
+			RestaurantID int `json:"restaurant_id"`
+			Overall      struct {
+				Count   int     `json:"count"`
+				Average float64 `json:"average"`
+			} `json:"overall"`
+		}
+		if e := s.get(ctx, s.reviews, "/reviews/summaries?restaurant_id="+strings.Join(ids, ","), &out); e != nil {
+			errs = append(errs, e)
+			for _, r := range batch {
+				stale[r.MetadataID] = true
+			}
+			continue
+		}
+		for _, sum := range out {
+			id, ok := metadataIDs[sum.RestaurantID]
+			if !ok {
+				continue
+			}
+			sums[id] += sum.Overall.Average * float64(sum.Overall.Count)
+			counts[id] += sum.Overall.Count
+		}
+	}
+
+	ratings := make(map[int]search.Rating, len(counts))
+	for id, n := range counts {
+		if n > 0 && !stale[id] {
+			ratings[id] = search.Rating{Average: sums[id] / float64(n), Count: n}
+		}
+	}
+	s.mu.Lock()
+	for id := range stale {
+		if r, ok := s.ratings[id]; ok {
+			ratings[id] = r
+		}
+	}
+	s.ratings = ratings
+	s.mu.Unlock()
+	err = errors.Join(errs...)
+	return err
+}
+
+func (s *Source) get(ctx context.Context, res *discovery.Resolver, path string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	base, err := res.BaseURL(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s -> %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(out)
+}
@@ -0,0 +1,51 @@
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean Earth radius.
+const EarthRadiusKm = 6371.0088
+
+// DistanceKm is the great-circle (haversine) distance between two points.
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	lat1r, lat2r := radians(lat1), radians(lat2)
+	dLat, dLng := lat2r-lat1r, radians(lng2-lng1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1r)*math.Cos(lat2r)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Box is a latitude/longitude bounding box in degrees.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// Around returns the smallest box containing every point within radiusKm of
+// (lat, lng). Near a pole the box spans all longitudes; across the
+// antimeridian MinLng > MaxLng.
+func Around(lat, lng, radiusKm float64) Box {
+	d := radiusKm / EarthRadiusKm
+	b := Box{MinLat: lat - degrees(d), MaxLat: lat + degrees(d), MinLng: -180, MaxLng: 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		b.MinLat, b.MaxLat = math.Max(b.MinLat, -90), math.Min(b.MaxLat, 90)
+		return b
+	}
+	// Widest longitude span is at the tangent latitude, not at lat itself
+	dLng := degrees(math.Asin(math.Sin(d) / math.Cos(radians(lat))))
+	if dLng >= 180 {
+		return b
+	}
+	b.MinLng, b.MaxLng = wrap(lng-dLng), wrap(lng+dLng)
+	return b
+}
+
+// wrap maps a longitude into [-180, 180).
+func wrap(lng float64) float64 {
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	return lng - 180
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
@@ -0,0 +1,96 @@
+package geo
+
+import "math"
+
+// MaxPrecision is the geohash length stored in the index (cells of about
+// 5m x 5m).
+const MaxPrecision = 9
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of a point at the given precision (1-12).
+func Encode(lat, lng float64, precision int) string {
+	latBits, lngBits := bits(precision)
+	return fromCell(cellIndex(lat+90, 180, latBits), cellIndex(lng+180, 360, lngBits), precision)
+}
+
+// bits splits the 5*precision geohash bits between the axes; longitude
+// takes the extra bit.
+func bits(precision int) (latBits, lngBits int) {
+	n := 5 * precision
+	return n / 2, n - n/2
+}
+
+// cellSize is the height and width in degrees of a cell at precision.
+func cellSize(precision int) (dLat, dLng float64) {
+	latBits, lngBits := bits(precision)
+	return 180 / math.Exp2(float64(latBits)), 360 / math.Exp2(float64(lngBits))
+}
+
+// cellIndex is the row (or column) of offset v in [0, span] when the span
+// is cut into 2^n cells; the far edge belongs to the last cell.
+func cellIndex(v, span float64, n int) uint64 {
+	cells := uint64(1) << n
+	i := uint64(math.Max(0, v) / span * float64(cells))
+	if i >= cells {
+		i = cells - 1
+	}
+	return i
+}
+
+// fromCell interleaves a cell's row and column into a geohash, starting
+// with a longitude bit.
+func fromCell(row, col uint64, precision int) string {
+	latBits, lngBits := bits(precision)
+	out := make([]byte, precision)
+	var ch, n byte
+	for b := 0; b < 5*precision; b++ {
+		var bit uint64
+		if b%2 == 0 {
+			lngBits--
+			bit = col >> lngBits & 1
+		} else {
+			latBits--
+			bit = row >> latBits & 1
+		}
+		ch = ch<<1 | byte(bit)
+		if n++; n == 5 {
+			out[b/5] = base32[ch]
+			ch, n = 0, 0
+		}
+	}
+	return string(out)
+}
+
+// Cover returns geohash cells that together contain the box, at the finest
+// precision needing no more than maxCells cells. A box with
+// MinLng > MaxLng crosses the antimeridian.
+func Cover(b Box, maxCells int) []string {
+	for p := MaxPrecision; p > 1; p-- {
+		if cells := cover(b, p, maxCells); cells != nil {
+			return cells
+		}
+	}
+	return cover(b, 1, math.MaxInt)
+}
+
+func cover(b Box, p, maxCells int) []string {
+	latBits, lngBits := bits(p)
+	r0, r1 := cellIndex(b.MinLat+90, 180, latBits), cellIndex(b.MaxLat+90, 180, latBits)
+	c0, c1 := cellIndex(b.MinLng+180, 360, lngBits), cellIndex(b.MaxLng+180, 360, lngBits)
+	cols := uint64(1) << lngBits
+	width := c1 - c0 + 1
+	if c1 < c0 {
+		width = cols - c0 + c1 + 1
+	}
+	if (r1-r0+1)*width > uint64(maxCells) {
+		return nil
+	}
+	out := make([]string, 0, (r1-r0+1)*width)
+	for r := r0; r <= r1; r++ {
+		for i := uint64(0); i < width; i++ {
+			out = append(out, fromCell(r, (c0+i)%cols, p))
+		}
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package geo
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxCoverCells bounds the range scans per query; larger radii use coarser
+// cells.
+const maxCoverCells = 32
+
+type entry struct {
+	hash     string
+	id       int
+	lat, lng float64
+}
+
+// Hit is a record within the query radius.
+type Hit struct {
+	ID         int
+	DistanceKm float64
+}
+
+// Index is an in-memory spatial index: points sorted by geohash, so every
+// cell is a contiguous range. It is safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	entries []entry
+	// Current geohash per id, to find the entry on update
+	hashes map[int]string
+	// Last version applied per id, kept after Remove, so late writes of an
+	// older version are ignored
+	versions map[int]int
+}
+
+func NewIndex() *Index {
+	return &Index{hashes: make(map[int]string), versions: make(map[int]int)}
+}
+
+// Put indexes (or moves) the point of record id at version.
+func (ix *Index) Put(id, version int, lat, lng float64) {
+	e := entry{hash: Encode(lat, lng, MaxPrecision), id: id, lat: lat, lng: lng}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.advance(id, version) {
+		return
+	}
+	ix.remove(id)
+	i := ix.search(e.hash, id)
+	ix.entries = append(ix.entries, entry{})
+	copy(ix.entries[i+1:], ix.entries[i:])
+	ix.entries[i] = e
+	ix.hashes[id] = e.hash
+}
+
+// Remove drops record id, e.g. when its location becomes unknown at version.
+func (ix *Index) Remove(id, version int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.advance(id, version) {
+		ix.remove(id)
+	}
+}
+
+// advance records version for id unless a newer one was applied.
+func (ix *Index) advance(id, version int) bool {
+	if cur, ok := ix.versions[id]; ok && cur > version {
+		return false
+	}
+	ix.versions[id] = version
+	return true
+}
+
+func (ix *Index) remove(id int) {
+	h, ok := ix.hashes[id]
+	if !ok {
+		return
+	}
+	if i := ix.search(h, id); i < len(ix.entries) && ix.entries[i].id == id {
+		ix.entries = append(ix.entries[:i], ix.entries[i+1:]...)
+	}
+	delete(ix.hashes, id)
+}
+
+// search is the position of (hash, id) in the sorted entries.
+func (ix *Index) search(hash string, id int) int {
+	return sort.Search(len(ix.entries), func(i int) bool {
+		e := ix.entries[i]
+		return e.hash > hash || e.hash == hash && e.id >= id
+	})
+}
+
+// Within returns the records within radiusKm of (lat, lng), nearest first
+// (ties by id), at most limit of them when limit > 0.
+func (ix *Index) Within(lat, lng, radiusKm float64, limit int) []Hit {
+	cells := Cover(Around(lat, lng, radiusKm), maxCoverCells)
+
+	ix.mu.RLock()
+	var hits []Hit
+	for _, cell := range cells {
+		i := sort.Search(len(ix.entries), func(i int) bool { return ix.entries[i].hash >= cell })
+		for ; i < len(ix.entries) && strings.HasPrefix(ix.entries[i].hash, cell); i++ {
+			e := ix.entries[i]
+			if d := DistanceKm(lat, lng, e.lat, e.lng); d <= radiusKm {
+				hits = append(hits, Hit{ID: e.id, DistanceKm: d})
+			}
+		}
+	}
+	ix.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].DistanceKm != hits[j].DistanceKm {
+			return hits[i].DistanceKm < hits[j].DistanceKm
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
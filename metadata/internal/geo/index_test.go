@@ -0,0 +1,69 @@
+package geo
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Updates can reach the index out of order; the newest version wins, also
+// when it removed the point.
+func TestPutIgnoresOlderVersions(t *testing.T) {
+	ix := NewIndex()
+	boston, austin := [2]float64{42.3601, -71.0589}, [2]float64{30.2672, -97.7431}
+
+	ix.Put(1, 2, boston[0], boston[1])
+	ix.Put(1, 1, austin[0], austin[1])
+	if hits := ix.Within(boston[0], boston[1], 1, 0); len(hits) != 1 {
+		t.Fatalf("near the current point: %v, want record 1", hits)
+	}
+	if hits := ix.Within(austin[0], austin[1], 1, 0); len(hits) != 0 {
+		t.Fatalf("near the stale point: %v, want none", hits)
+	}
+
+	ix.Remove(1, 3)
+	ix.Put(1, 2, boston[0], boston[1])
+	if hits := ix.Within(boston[0], boston[1], 1, 0); len(hits) != 0 {
+		t.Fatalf("after removal at a newer version: %v, want none", hits)
+	}
+}
+
+const benchRecords = 100_000
+
+// benchIndex spreads n points over the continental US, denser around a few
+// metro areas as real restaurant data would be.
+func benchIndex(b *testing.B, n int) *Index {
+	b.Helper()
+	metros := [][2]float64{{40.7128, -74.0060}, {42.3601, -71.0589}, {41.8781, -87.6298}, {37.7749, -122.4194}, {30.2672, -97.7431}}
+	rnd := rand.New(rand.NewSource(1))
+	ix := NewIndex()
+	for id := 1; id <= n; id++ {
+		if id%2 == 0 {
+			c := metros[rnd.Intn(len(metros))]
+			ix.Put(id, 1, c[0]+rnd.NormFloat64()*0.2, c[1]+rnd.NormFloat64()*0.2)
+		} else {
+			ix.Put(id, 1, 25+rnd.Float64()*24, -124+rnd.Float64()*57)
+		}
+	}
+	return ix
+}
+
+func BenchmarkNearby(b *testing.B) {
+	ix := benchIndex(b, benchRecords)
+	for _, tc := range []struct {
+		name     string
+		radiusKm float64
+	}{
+		{"1km", 1},
+		{"5km", 5},
+		{"25km", 25},
+		{"100km", 100},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// Downtown Boston: a dense metro
+				ix.Within(42.3601, -71.0589, tc.radiusKm, 20)
+			}
+		})
+	}
+}
@@ -3,12 +3,21 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	ctrl "github.com/ChristopherLeo15/opentable/metadata/internal/controller/metadata"
 	m "github.com/ChristopherLeo15/opentable/metadata/model"
-	repoerr "github.com/ChristopherLeo15/opentable/metadata/internal/repository/memory"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
 )
 
 type Handler struct {
@@ -19,14 +28,18 @@ func New(c *ctrl.Controller) *Handler {
 	return &Handler{c: c}
 }
 
-func (h *Handler) Router() http.Handler {
+// Router wires the routes; mw (auth, rate limiting, ...) wraps every route.
+func (h *Handler) Router(mw ...func(http.Handler) http.Handler) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/metadata", h.handleMetadata)
+	mux.HandleFunc("/metadata/search", h.searchMetadata)
+	mux.HandleFunc("/metadata/nearby", h.nearbyMetadata)
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	return mux
+	mux.Handle("/metrics", metrics.Handler())
+	return metrics.Middleware(mux, mw...)
 }
 
 func (h *Handler) handleMetadata(w http.ResponseWriter, r *http.Request) {
@@ -35,33 +48,137 @@ func (h *Handler) handleMetadata(w http.ResponseWriter, r *http.Request) {
 		h.getMetadata(w, r)
 	case http.MethodPost:
 		h.postMetadata(w, r)
+	case http.MethodPut:
+		h.putMetadata(w, r)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *Handler) getMetadata(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("id")
 	if q == "" {
-		// List all records
-		writeJSON(w, http.StatusOK, h.c.List(r.Context()))
+		// List all records, optionally filtered by city and cuisine
+		f := ctrl.Filter{
+			City:        strings.TrimSpace(r.URL.Query().Get("city")),
+			CuisineType: strings.TrimSpace(r.URL.Query().Get("cuisine_type")),
+		}
+		switch v := strings.TrimSpace(r.URL.Query().Get("open_at")); v {
+		case "":
+		case "now":
+			f.OpenAt = time.Now()
+		default:
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				problem.Write(w, r, apperr.Field("open_at", "must be an RFC 3339 time such as 2025-06-01T19:30:00-04:00, or now"))
+				return
+			}
+			f.OpenAt = t
+		}
+		writeJSON(w, http.StatusOK, h.c.List(r.Context(), f))
 		return
 	}
 	id, err := strconv.Atoi(q)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 	item, err := h.c.GetByID(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, repoerr.ErrNotFound) {
-			http.Error(w, "not found", http.StatusNotFound)
+		problem.Write(w, r, err)
+		return
+	}
+	tag := etag.Format(item.Version)
+	if item.OpenNow != nil {
+		// open_now is computed per request: fold it into the validator and
+		// keep cached copies short-lived, as it flips without a new version
+		tag = etag.Derive(item.Version, openState(*item.OpenNow))
+		w.Header().Set("Cache-Control", "max-age=60")
+	}
+	w.Header().Set("ETag", tag)
+	if etag.NoneMatch(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+// searchMetadata: GET /metadata/search?q=&cuisine_type=&city=&price_range=&boost=rating&limit=
+func (h *Handler) searchMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	req := ctrl.SearchRequest{
+		Text:        strings.TrimSpace(q.Get("q")),
+		CuisineType: strings.TrimSpace(q.Get("cuisine_type")),
+		City:        strings.TrimSpace(q.Get("city")),
+		PriceRange:  strings.TrimSpace(q.Get("price_range")),
+	}
+	switch q.Get("boost") {
+	case "":
+	case "rating":
+		req.BoostRating = true
+	default:
+		problem.Write(w, r, apperr.Field("boost", "must be rating"))
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			problem.Write(w, r, apperr.Field("limit", "must be a positive integer"))
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		req.Limit = n
+	}
+	out, err := h.c.Search(r.Context(), req)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, item)
+	writeJSON(w, http.StatusOK, out)
+}
+
+// nearbyMetadata: GET /metadata/nearby?lat=&lng=&radius_km=&limit=
+func (h *Handler) nearbyMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	var fields []apperr.FieldError
+	number := func(name string) float64 {
+		v := strings.TrimSpace(q.Get(name))
+		if v == "" {
+			fields = append(fields, apperr.FieldError{Field: name, Message: "is required"})
+			return 0
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: name, Message: "must be a number"})
+		}
+		return f
+	}
+	lat, lng, radius := number("lat"), number("lng"), number("radius_km")
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			fields = append(fields, apperr.FieldError{Field: "limit", Message: "must be a positive integer"})
+		}
+		limit = n
+	}
+	if len(fields) > 0 {
+		problem.Write(w, r, apperr.Validation(fields...))
+		return
+	}
+	out, err := h.c.Nearby(r.Context(), lat, lng, radius, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
 }
 
 func (h *Handler) Health(w http.ResponseWriter, _ *http.Request) {
@@ -70,21 +187,187 @@ func (h *Handler) Health(w http.ResponseWriter, _ *http.Request) {
 
 func (h *Handler) postMetadata(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	var in m.Metadata
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "invalid json body", http.StatusBadRequest)
+	in, ok := decodeMetadata(w, r)
+	if !ok {
 		return
 	}
 	out, err := h.c.Add(r.Context(), in)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", etag.Format(out.Version))
 	writeJSON(w, http.StatusCreated, out)
 }
 
+func (h *Handler) putMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	pre, err := etag.IfMatch(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	in, ok := decodeMetadata(w, r)
+	if !ok {
+		return
+	}
+	out, err := h.c.Update(r.Context(), id, pre, in)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	writeJSON(w, http.StatusOK, out)
+}
+
 // ----- Support function -----
 
+// openState encodes open_now for etag.Derive.
+func openState(open bool) int {
+	if open {
+		return 1
+	}
+	return 0
+}
+
+// Largest metadata body accepted; hours with many exceptions stay far below.
+const maxBodyBytes = 1 << 20
+
+// decodeMetadata decodes the body and reports every unknown field, nested
+// ones included (as "hours.weekly.monday[0].opn"), in one validation
+// problem. It writes the problem itself and returns ok=false on failure.
+func decodeMetadata(w http.ResponseWriter, r *http.Request) (m.Metadata, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, "body is too large")
+		} else {
+			problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		}
+		return m.Metadata{}, false
+	}
+	var in m.Metadata
+	if err := json.Unmarshal(body, &in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return m.Metadata{}, false
+	}
+	if unknown := unknownFields(body, reflect.TypeOf(in), ""); len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i].Field < unknown[j].Field })
+		problem.Write(w, r, apperr.Validation(unknown...))
+		return m.Metadata{}, false
+	}
+	return in, true
+}
+
+// unknownFields walks data alongside type t and lists object keys t has no
+// field for. data has already decoded into t, so shapes match.
+func unknownFields(data json.RawMessage, t reflect.Type, path string) []apperr.FieldError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []apperr.FieldError
+	switch t.Kind() {
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			return nil // null, or a type with its own JSON form
+		}
+		fields := jsonFields(t)
+		for k, v := range obj {
+			ft, ok := fieldType(fields, k)
+			if !ok {
+				out = append(out, apperr.FieldError{Field: join(path, k), Message: "unknown field"})
+				continue
+			}
+			out = append(out, unknownFields(v, ft, join(path, k))...)
+		}
+	case reflect.Map:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			return nil
+		}
+		for k, v := range obj {
+			out = append(out, unknownFields(v, t.Elem(), join(path, k))...)
+		}
+	case reflect.Slice, reflect.Array:
+		var arr []json.RawMessage
+		if json.Unmarshal(data, &arr) != nil {
+			return nil
+		}
+		for i, v := range arr {
+			out = append(out, unknownFields(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return out
+}
+
+// jsonFields maps the names encoding/json decodes into struct type t to the
+// field types: the tag name, else the Go name, of exported fields, with
+// untagged embedded structs promoting theirs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	var promoted []map[string]reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			promoted = append(promoted, jsonFields(ft))
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out[name] = f.Type
+	}
+	// Outer fields win over promoted ones, as in encoding/json
+	for _, fields := range promoted {
+		for name, ft := range fields {
+			if _, ok := out[name]; !ok {
+				out[name] = ft
+			}
+		}
+	}
+	return out
+}
+
+// fieldType finds key among fields the way encoding/json does: an exact
+// match first, then a case-insensitive one.
+func fieldType(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if ft, ok := fields[key]; ok {
+		return ft, true
+	}
+	for name, ft := range fields {
+		if strings.EqualFold(name, key) {
+			return ft, true
+		}
+	}
+	return nil, false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
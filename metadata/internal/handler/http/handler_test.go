@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int      // 0 when the body decodes
+		fields []string // unknown fields reported, sorted
+	}{
+		{
+			name: "known fields",
+			body: `{"name":"A","location":{"latitude":1,"longitude":2},"hours":{"timezone":"UTC","weekly":{"monday":[{"open":"09:00","close":"17:00"}]}}}`,
+		},
+		{
+			name: "names match case-insensitively like encoding/json",
+			body: `{"Name":"A","LOCATION":{"Latitude":1},"hours":{"Weekly":{"monday":[{"OPEN":"09:00"}]}}}`,
+		},
+		{
+			name:   "unknown top-level field",
+			body:   `{"name":"A","cusine_type":"thai"}`,
+			status: http.StatusBadRequest,
+			fields: []string{"cusine_type"},
+		},
+		{
+			name:   "unknown nested fields",
+			body:   `{"location":{"latitude":1,"lat":3},"hours":{"timzone":"UTC","weekly":{"monday":[{"open":"09:00"},{"opn":"10:00"}]},"exceptions":[{"date":"2026-12-25","closd":true}]}}`,
+			status: http.StatusBadRequest,
+			fields: []string{"hours.exceptions[0].closd", "hours.timzone", "hours.weekly.monday[1].opn", "location.lat"},
+		},
+		{
+			name:   "malformed json",
+			body:   `{"name":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "body over the cap",
+			body:   `{"name":"` + strings.Repeat("a", maxBodyBytes) + `"}`,
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/metadata", strings.NewReader(tt.body))
+			_, ok := decodeMetadata(rec, req)
+			if ok != (tt.status == 0) {
+				t.Fatalf("ok = %v, response %d %s", ok, rec.Code, rec.Body)
+			}
+			if ok {
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var p struct {
+				Errors []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			for _, e := range p.Errors {
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields = %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -1,24 +1,41 @@
 package memory
 
 import (
-	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	m "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	"github.com/ChristopherLeo15/opentable/pkg/idgen"
 )
 
 var (
-	ErrNotFound = errors.New("metadata not found")
+	ErrNotFound = apperr.NotFound("metadata not found")
+	ErrConflict = apperr.Conflict("metadata id already exists")
 )
 
 type Repo struct {
 	// Mutex for safe concurrent access
 	mu   sync.RWMutex
 	data []m.Metadata
+	seq  idgen.Sequence
+
+	// Indexes hold positions in data, kept sorted so lookups return
+	// records in insertion order
+	byID      map[int]int
+	byCity    map[string][]int
+	byCuisine map[string][]int
 }
 
 func New() *Repo {
-	return &Repo{data: make([]m.Metadata, 0, 16)}
+	return &Repo{
+		data:      make([]m.Metadata, 0, 16),
+		byID:      make(map[int]int, 16),
+		byCity:    make(map[string][]int),
+		byCuisine: make(map[string][]int),
+	}
 }
 
 func (r *Repo) GetAll() []m.Metadata {
@@ -29,19 +46,109 @@ func (r *Repo) GetAll() []m.Metadata {
 	return out
 }
 
+func (r *Repo) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.data)
+}
+
 func (r *Repo) GetByID(id int) (m.Metadata, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	for _, x := range r.data {
-		if x.ID == id {
-			return x, nil
-		}
+	if i, ok := r.byID[id]; ok {
+		return r.data[i], nil
 	}
 	return m.Metadata{}, ErrNotFound
 }
 
-func (r *Repo) Add(x m.Metadata) {
+// ListByCity returns records in the given city (case-insensitive).
+func (r *Repo) ListByCity(city string) []m.Metadata {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.collect(r.byCity[indexKey(city)])
+}
+
+// ListByCuisine returns records with the given cuisine (case-insensitive).
+func (r *Repo) ListByCuisine(cuisine string) []m.Metadata {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.collect(r.byCuisine[indexKey(cuisine)])
+}
+
+// Add stores x, assigning the next ID when x.ID is zero. A caller-supplied
+// ID that is already taken yields ErrConflict.
+func (r *Repo) Add(x m.Metadata) (m.Metadata, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if x.ID == 0 {
+		x.ID = r.seq.Next()
+	} else {
+		if _, ok := r.byID[x.ID]; ok {
+			return m.Metadata{}, ErrConflict
+		}
+		r.seq.Observe(x.ID)
+	}
+	x.Version = 1
+	i := len(r.data)
 	r.data = append(r.data, x)
+	r.byID[x.ID] = i
+	r.byCity[indexKey(x.City)] = append(r.byCity[indexKey(x.City)], i)
+	r.byCuisine[indexKey(x.CuisineType)] = append(r.byCuisine[indexKey(x.CuisineType)], i)
+	return x, nil
+}
+
+// Update replaces the record with x.ID if its current version satisfies
+// pre, and bumps the version.
+func (r *Repo) Update(x m.Metadata, pre etag.Precondition) (m.Metadata, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.byID[x.ID]
+	if !ok {
+		return m.Metadata{}, ErrNotFound
+	}
+	old := r.data[i]
+	if !pre.Allows(old.Version) {
+		return m.Metadata{}, apperr.PreconditionFailed("metadata %d has changed (now version %d)", x.ID, old.Version)
+	}
+	x.Version = old.Version + 1
+	reindex(r.byCity, indexKey(old.City), indexKey(x.City), i)
+	reindex(r.byCuisine, indexKey(old.CuisineType), indexKey(x.CuisineType), i)
+	r.data[i] = x
+	return x, nil
+}
+
+func (r *Repo) collect(positions []int) []m.Metadata {
+	out := make([]m.Metadata, 0, len(positions))
+	for _, i := range positions {
+		out = append(out, r.data[i])
+	}
+	return out
+}
+
+func indexKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// reindex moves position i from the from bucket to the to bucket, keeping
+// both sorted.
+func reindex(idx map[string][]int, from, to string, i int) {
+	if from == to {
+		return
+	}
+	old := idx[from]
+	if j := sort.SearchInts(old, i); j < len(old) && old[j] == i {
+		old = append(old[:j], old[j+1:]...)
+	}
+	if len(old) == 0 {
+		delete(idx, from)
+	} else {
+		idx[from] = old
+	}
+
+	cur := idx[to]
+	j := sort.SearchInts(cur, i)
+	cur = append(cur, 0)
+	copy(cur[j+1:], cur[j:])
+	cur[j] = i
+	idx[to] = cur
 }
\ No newline at end of file
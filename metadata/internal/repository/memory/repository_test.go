@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	m "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+)
+
+// Concurrent adds get distinct IDs, and a taken client-supplied ID conflicts
+// for every caller but one.
+func TestRepoConcurrentAdd(t *testing.T) {
+	const workers = 100
+	r := New()
+	var wg sync.WaitGroup
+	ids := make(chan int, workers)
+	var mu sync.Mutex
+	conflicts := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			x, err := r.Add(m.Metadata{Name: "n"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- x.ID
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := r.Add(m.Metadata{ID: 1000, Name: "fixed"})
+			if errors.Is(err, ErrConflict) {
+				mu.Lock()
+				conflicts++
+				mu.Unlock()
+			} else if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != workers {
+		t.Fatalf("got %d ids, want %d", len(seen), workers)
+	}
+	if conflicts != workers-1 {
+		t.Fatalf("got %d conflicts for id 1000, want %d", conflicts, workers-1)
+	}
+	if n := r.Count(); n != workers+1 {
+		t.Fatalf("count = %d, want %d", n, workers+1)
+	}
+}
+
+// Moving records between cities and cuisines keeps every bucket pointing at
+// the right positions, in insertion order, with keys matched loosely.
+func TestUpdateKeepsIndexesConsistent(t *testing.T) {
+	cities := []string{"Boston", "New York", "Chicago"}
+	cuisines := []string{"Thai", "Greek"}
+	r := New()
+	for i := 0; i < 30; i++ {
+		if _, err := r.Add(m.Metadata{Name: fmt.Sprint(i), City: cities[i%3], CuisineType: cuisines[i%2]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := 1; id <= 30; id += 3 {
+		x, _ := r.GetByID(id)
+		x.City = "  new   YORK "
+		x.CuisineType = cuisines[(id+1)%2]
+		if _, err := r.Update(x, etag.Precondition{Any: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A failed precondition changes nothing
+	x, _ := r.GetByID(2)
+	x.City = "Denver"
+	if _, err := r.Update(x, etag.Precondition{Versions: []int{7}}); err == nil {
+		t.Fatal("stale update succeeded")
+	}
+
+	all := r.GetAll()
+	for _, x := range all {
+		if got, err := r.GetByID(x.ID); err != nil || !reflect.DeepEqual(got, x) {
+			t.Fatalf("GetByID(%d) = %+v, %v; want %+v", x.ID, got, err, x)
+		}
+	}
+	filter := func(match func(m.Metadata) bool) []m.Metadata {
+		out := []m.Metadata{}
+		for _, x := range all {
+			if match(x) {
+				out = append(out, x)
+			}
+		}
+		return out
+	}
+	for _, city := range append(cities, "Denver") {
+		want := filter(func(x m.Metadata) bool { return indexKey(x.City) == indexKey(city) })
+		if got := r.ListByCity(strings.ToUpper(city)); !reflect.DeepEqual(got, want) {
+			t.Errorf("ListByCity(%q) = %d records, want %d in insertion order", city, len(got), len(want))
+		}
+	}
+	if n := len(r.ListByCity("new york")); n != 20 {
+		t.Errorf("new york has %d records, want 20", n)
+	}
+	for _, c := range cuisines {
+		want := filter(func(x m.Metadata) bool { return x.CuisineType == c })
+		if got := r.ListByCuisine(c); !reflect.DeepEqual(got, want) {
+			t.Errorf("ListByCuisine(%q) = %d records, want %d in insertion order", c, len(got), len(want))
+		}
+	}
+}
+
+const benchRecords = 100_000
+
+var benchCities = []string{"Boston", "New York", "Chicago", "Seattle", "Austin", "Denver", "Miami", "Portland"}
+
+// benchRepo fills a store with n records and returns it with a snapshot for
+// the linear-scan baselines, which replay the lookups the store did before
+// it was indexed.
+func benchRepo(b *testing.B, n int) (*Repo, []m.Metadata) {
+	b.Helper()
+	r := New()
+	for i := 0; i < n; i++ {
+		if _, err := r.Add(m.Metadata{Name: fmt.Sprint(i), City: benchCities[i%len(benchCities)], CuisineType: "Thai"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return r, r.GetAll()
+}
+
+// spread maps iteration i to an ID in [1, n], visiting the whole range
+// rather than only its start.
+func spread(i, n int) int {
+	return i*7919%n + 1
+}
+
+func BenchmarkGetByID(b *testing.B) {
+	r, all := benchRepo(b, benchRecords)
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.GetByID(spread(i, benchRecords)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			id := spread(i, benchRecords)
+			for _, x := range all {
+				if x.ID == id {
+					break
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkListByCity(b *testing.B) {
+	r, all := benchRepo(b, benchRecords)
+	b.Run("index", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.ListByCity(benchCities[i%len(benchCities)])
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			city := strings.ToLower(benchCities[i%len(benchCities)])
+			var out []m.Metadata
+			for _, x := range all {
+				if strings.ToLower(x.City) == city {
+					out = append(out, x)
+				}
+			}
+		}
+	})
+}
+
+// Add into a store already holding benchRecords records, with a
+// caller-supplied ID so the duplicate check runs.
+func BenchmarkAdd(b *testing.B) {
+	b.Run("index", func(b *testing.B) {
+		r, _ := benchRepo(b, benchRecords)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := r.Add(m.Metadata{ID: benchRecords + i + 1, Name: "n", City: "Boston"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		_, all := benchRepo(b, benchRecords)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			id := benchRecords + i + 1
+			for _, x := range all {
+				if x.ID == id {
+					b.Fatal("duplicate")
+				}
+			}
+			all = append(all, m.Metadata{ID: id, Name: "n", City: "Boston"})
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokens lowercases s and splits it into words of letters and digits.
+func tokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxEdits is the typo budget for a query word: none for short words, where
+// one edit already changes the meaning, two for long ones.
+func maxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matchScore rates how well query word q matches indexed word w: 1 for an
+// exact match, 0.8 for a prefix (so "ital" finds "italian"), less for each
+// typo within budget, and less again for a prefix with typos (so "piza"
+// finds "pizzeria"); 0 otherwise.
+func matchScore(q, w string) float64 {
+	if q == w {
+		return 1
+	}
+	if len(q) >= 3 && strings.HasPrefix(w, q) {
+		return 0.8
+	}
+	budget := maxEdits(q)
+	if budget == 0 {
+		return 0
+	}
+	if d := distance(q, w, budget); d <= budget {
+		return 0.7 - 0.15*float64(d-1)
+	}
+	if n := utf8.RuneCountInString(q); utf8.RuneCountInString(w) > n {
+		if d := distance(q, prefix(w, n), budget); d <= budget {
+			return 0.55 - 0.15*float64(d-1)
+		}
+	}
+	return 0
+}
+
+// prefix returns the first n runes of s without copying.
+func prefix(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// distance is the optimal string alignment distance (Levenshtein plus
+// adjacent transpositions, so "itlaian" is one typo). It gives up once the
+// distance exceeds limit and returns limit+1.
+func distance(a, b string, limit int) int {
+	// Most vocabulary words differ too much in length; reject them before
+	// allocating anything
+	if d := utf8.RuneCountInString(a) - utf8.RuneCountInString(b); d > limit || -d > limit {
+		return limit + 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	var buf [3 * 32]int
+	rows := buf[:]
+	if n := 3 * (len(rb) + 1); n > len(buf) {
+		rows = make([]int, n)
+	}
+	prev2, prev, cur := rows[:len(rb)+1], rows[len(rb)+1:2*(len(rb)+1)], rows[2*(len(rb)+1):]
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
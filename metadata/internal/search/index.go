@@ -0,0 +1,241 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	m "github.com/ChristopherLeo15/opentable/metadata/model"
+)
+
+// Field weights: a hit in the name counts most.
+var fieldWeights = [...]float64{name: 3, cuisine: 2, city: 1.5, address: 1}
+
+const (
+	name = iota
+	cuisine
+	city
+	address
+	numFields
+)
+
+// Facet dimensions.
+const (
+	FacetCuisine = "cuisine_type"
+	FacetCity    = "city"
+	FacetPrice   = "price_range"
+)
+
+var facetNames = [...]string{FacetCuisine, FacetCity, FacetPrice}
+
+const numFacets = len(facetNames)
+
+type doc struct {
+	version int
+	fields  [numFields][]string
+	// Facet values as given, and lowercased for matching filters
+	facets    [numFacets]string
+	facetKeys [numFacets]string
+}
+
+// Index is an in-memory search index over metadata records. It is safe for
+// concurrent use.
+type Index struct {
+	mu   sync.RWMutex
+	docs map[int]doc
+	// vocab maps each indexed word to the records containing it, so fuzzy
+	// matching scans distinct words rather than records
+	vocab map[string]map[int]bool
+}
+
+func NewIndex() *Index {
+	return &Index{docs: make(map[int]doc), vocab: make(map[string]map[int]bool)}
+}
+
+// Put indexes (or reindexes) a record. Writes can reach the index out of
+// order, so a version older than the one indexed is ignored.
+func (ix *Index) Put(x m.Metadata) {
+	d := doc{version: x.Version, facets: [numFacets]string{x.CuisineType, x.City, x.PriceRange}}
+	for f, v := range d.facets {
+		d.facetKeys[f] = strings.ToLower(v)
+	}
+	d.fields[name] = tokens(x.Name)
+	d.fields[cuisine] = tokens(x.CuisineType)
+	d.fields[city] = tokens(x.City)
+	d.fields[address] = tokens(x.Address)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if cur, ok := ix.docs[x.ID]; ok && cur.version > x.Version {
+		return
+	}
+	ix.remove(x.ID)
+	ix.docs[x.ID] = d
+	for _, f := range d.fields {
+		for _, w := range f {
+			if ix.vocab[w] == nil {
+				ix.vocab[w] = make(map[int]bool)
+			}
+			ix.vocab[w][x.ID] = true
+		}
+	}
+}
+
+func (ix *Index) remove(id int) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, f := range d.fields {
+		for _, w := range f {
+			delete(ix.vocab[w], id)
+			if len(ix.vocab[w]) == 0 {
+				delete(ix.vocab, w)
+			}
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Query is a search request. Filters match facet values case-insensitively.
+type Query struct {
+	Text    string
+	Filters map[string]string
+	// Boost, when set, scales scores by the restaurant's rating (see
+	// RatingBoost)
+	Boost func(id int) float64
+	Limit int
+}
+
+// Hit is a matching record id and its score.
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+// Result holds the best hits, the total number of matches, and facet
+// counts. Each facet is counted with every filter applied except its own,
+// so clients can show how many results picking another value would give.
+type Result struct {
+	Total  int
+	Hits   []Hit
+	Facets map[string]map[string]int
+}
+
+// Search runs q. Every query word must match some field of a record,
+// exactly, as a prefix or within its typo budget.
+func (ix *Index) Search(q Query) Result {
+	var filters [numFacets]string
+	for f, dim := range facetNames {
+		filters[f] = strings.ToLower(strings.TrimSpace(q.Filters[dim]))
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var res Result
+	// Counts per lowercase value, labelled with the first spelling seen
+	var counts [numFacets]map[string]int
+	var labels [numFacets]map[string]string
+	for f := range counts {
+		counts[f] = make(map[string]int)
+		labels[f] = make(map[string]string)
+	}
+	visit := func(id int, score float64) {
+		d := ix.docs[id]
+		failed := -1
+		for f, want := range filters {
+			if want != "" && d.facetKeys[f] != want {
+				if failed != -1 {
+					failed = numFacets
+					break
+				}
+				failed = f
+			}
+		}
+		// Disjunctive facets: a record missing only the filter on one
+		// dimension still counts towards that dimension
+		for f := range counts {
+			if (failed == -1 || failed == f) && d.facets[f] != "" {
+				key := d.facetKeys[f]
+				if _, ok := labels[f][key]; !ok {
+					labels[f][key] = d.facets[f]
+				}
+				counts[f][key]++
+			}
+		}
+		if failed != -1 {
+			return
+		}
+		if q.Boost != nil {
+			score *= q.Boost(id)
+		}
+		res.Hits = append(res.Hits, Hit{ID: id, Score: score})
+	}
+
+	words := tokens(q.Text)
+	if len(words) == 0 {
+		for id := range ix.docs {
+			visit(id, 1)
+		}
+	} else {
+		// Text scores for records matching every query word
+		scores := ix.matchWord(words[0])
+		for _, qw := range words[1:] {
+			wordScores := ix.matchWord(qw)
+			for id := range scores {
+				if s, ok := wordScores[id]; ok {
+					scores[id] += s
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+		for id, score := range scores {
+			visit(id, score)
+		}
+	}
+
+	res.Facets = make(map[string]map[string]int, numFacets)
+	for f, dim := range facetNames {
+		res.Facets[dim] = make(map[string]int, len(counts[f]))
+		for key, n := range counts[f] {
+			res.Facets[dim][labels[f][key]] = n
+		}
+	}
+	res.Total = len(res.Hits)
+	sort.Slice(res.Hits, func(i, j int) bool {
+		if res.Hits[i].Score != res.Hits[j].Score {
+			return res.Hits[i].Score > res.Hits[j].Score
+		}
+		return res.Hits[i].ID < res.Hits[j].ID
+	})
+	if q.Limit > 0 && len(res.Hits) > q.Limit {
+		res.Hits = res.Hits[:q.Limit]
+	}
+	return res
+}
+
+// matchWord returns, for every record matching qw, the best weighted match
+// score over its fields.
+func (ix *Index) matchWord(qw string) map[int]float64 {
+	out := make(map[int]float64)
+	for w, ids := range ix.vocab {
+		s := matchScore(qw, w)
+		if s == 0 {
+			continue
+		}
+		for id := range ids {
+			best := out[id]
+			for f, words := range ix.docs[id].fields {
+				for _, dw := range words {
+					if dw == w && s*fieldWeights[f] > best {
+						best = s * fieldWeights[f]
+					}
+				}
+			}
+			out[id] = best
+		}
+	}
+	return out
+}
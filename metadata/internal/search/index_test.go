@@ -0,0 +1,78 @@
+package search
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	m "github.com/ChristopherLeo15/opentable/metadata/model"
+)
+
+// Updates can reach the index out of order; the newest version wins.
+func TestPutIgnoresOlderVersions(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(m.Metadata{ID: 1, Name: "Golden Dragon", CuisineType: "Chinese", City: "Boston", Version: 2})
+	ix.Put(m.Metadata{ID: 1, Name: "Casa Roma", CuisineType: "Italian", City: "Boston", Version: 1})
+
+	if got := ix.Search(Query{Text: "dragon"}); got.Total != 1 {
+		t.Fatalf("search for the current name: %d hits, want 1", got.Total)
+	}
+	if got := ix.Search(Query{Text: "roma"}); got.Total != 0 {
+		t.Fatalf("search for the stale name: %d hits, want 0", got.Total)
+	}
+
+	ix.Put(m.Metadata{ID: 1, Name: "Casa Roma", CuisineType: "Italian", City: "Boston", Version: 3})
+	if got := ix.Search(Query{Text: "roma"}); got.Total != 1 {
+		t.Fatalf("search after a newer update: %d hits, want 1", got.Total)
+	}
+}
+
+const benchRecords = 100_000
+
+var (
+	benchWords    = []string{"trattoria", "roma", "sushi", "zen", "golden", "dragon", "bistro", "casa", "grill", "garden", "harbor", "corner", "little", "royal", "spice", "noodle", "taco", "burger", "olive", "maple"}
+	benchCuisines = []string{"Italian", "Japanese", "Chinese", "Mexican", "French", "Indian", "Thai", "American", "Greek", "Korean"}
+	benchCities   = []string{"Boston", "New York", "Chicago", "Seattle", "Austin", "Denver", "Miami", "Portland"}
+	benchPrices   = []string{"$", "$$", "$$$", "$$$$"}
+)
+
+// benchIndex builds an index of n records with a realistic vocabulary:
+// common name words plus a per-record unique word.
+func benchIndex(b *testing.B, n int) *Index {
+	b.Helper()
+	rnd := rand.New(rand.NewSource(1))
+	ix := NewIndex()
+	for id := 1; id <= n; id++ {
+		ix.Put(m.Metadata{
+			ID:          id,
+			Name:        fmt.Sprintf("%s %s %d", benchWords[rnd.Intn(len(benchWords))], benchWords[rnd.Intn(len(benchWords))], id),
+			CuisineType: benchCuisines[rnd.Intn(len(benchCuisines))],
+			PriceRange:  benchPrices[rnd.Intn(len(benchPrices))],
+			Address:     fmt.Sprintf("%d Main St", rnd.Intn(999)+1),
+			City:        benchCities[rnd.Intn(len(benchCities))],
+		})
+	}
+	return ix
+}
+
+func BenchmarkSearch(b *testing.B) {
+	ix := benchIndex(b, benchRecords)
+	queries := []struct {
+		name string
+		q    Query
+	}{
+		{"exact", Query{Text: "sushi", Limit: 20}},
+		{"typo", Query{Text: "itlaian", Limit: 20}},
+		{"prefix", Query{Text: "trat", Limit: 20}},
+		{"two-words-filtered", Query{Text: "golden dragon", Filters: map[string]string{FacetCity: "boston"}, Limit: 20}},
+		{"facets-only", Query{Filters: map[string]string{FacetCuisine: "thai", FacetPrice: "$$"}, Limit: 20}},
+	}
+	for _, tc := range queries {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ix.Search(tc.q)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package search
+
+// Rating is a restaurant's review summary.
+type Rating struct {
+	Average float64
+	Count   int
+}
+
+// Bayesian prior: ratings are pulled towards 3 stars as if every record had
+// priorCount extra reviews, so one 5-star review doesn't top the list.
+const (
+	priorMean  = 3.0
+	priorCount = 5.0
+)
+
+// RatingBoost turns a rating into a score multiplier between 0.5 (1 star)
+// and 1.5 (5 stars); records without reviews get 1.
+func RatingBoost(r Rating, ok bool) float64 {
+	if !ok || r.Count == 0 {
+		return 1
+	}
+	n := float64(r.Count)
+	bayes := (priorMean*priorCount + r.Average*n) / (priorCount + n)
+	return 1 + (bayes-priorMean)/4
+}
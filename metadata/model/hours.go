@@ -0,0 +1,198 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/schedule"
+)
+
+// Weekdays are the keys of Hours.Weekly.
+var Weekdays = schedule.Weekdays
+
+// DateLayout is the layout of Exception.Date.
+const DateLayout = "2006-01-02"
+
+// Hours is a restaurant's schedule in its own timezone. An interval whose
+// close is not after its open runs past midnight ("18:00"-"02:00"); "24:00"
+// closes at midnight.
+type Hours struct {
+	// IANA name, e.g. "America/New_York"
+	Timezone   string                `json:"timezone"`
+	Weekly     map[string][]Interval `json:"weekly"`
+	Exceptions []Exception           `json:"exceptions,omitempty"`
+}
+
+type Interval struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// Exception replaces the weekly hours on one local date: closed all day,
+// or open for the given intervals only.
+type Exception struct {
+	Date      string     `json:"date"`
+	Closed    bool       `json:"closed,omitempty"`
+	Intervals []Interval `json:"intervals,omitempty"`
+	Note      string     `json:"note,omitempty"`
+}
+
+const maxExceptions = 400
+
+func (h *Hours) normalize() {
+	h.Timezone = strings.TrimSpace(h.Timezone)
+	// Day names are case-insensitive; keys naming the same day twice
+	// ("Monday" and "monday") are kept as sent so validation rejects them
+	days := make(map[string]int, len(h.Weekly))
+	for day := range h.Weekly {
+		days[strings.ToLower(strings.TrimSpace(day))]++
+	}
+	weekly := make(map[string][]Interval, len(h.Weekly))
+	for day, ivs := range h.Weekly {
+		key := strings.ToLower(strings.TrimSpace(day))
+		if days[key] > 1 {
+			key = strings.TrimSpace(day)
+		}
+		weekly[key] = ivs
+	}
+	h.Weekly = weekly
+	for i := range h.Exceptions {
+		h.Exceptions[i].Date = strings.TrimSpace(h.Exceptions[i].Date)
+		h.Exceptions[i].Note = strings.TrimSpace(h.Exceptions[i].Note)
+	}
+	sort.SliceStable(h.Exceptions, func(i, j int) bool { return h.Exceptions[i].Date < h.Exceptions[j].Date })
+}
+
+func (h Hours) fieldErrors() []apperr.FieldError {
+	var fields []apperr.FieldError
+	add := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: "hours." + field, Message: msg})
+	}
+
+	if h.Timezone == "" {
+		add("timezone", "is required")
+	} else if _, err := schedule.Location(h.Timezone); err != nil {
+		add("timezone", "must be an IANA timezone such as America/New_York")
+	}
+
+	known := make(map[string]bool, len(Weekdays))
+	for _, d := range Weekdays {
+		known[d] = true
+	}
+	days := make([]string, 0, len(h.Weekly))
+	for d := range h.Weekly {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+	for _, d := range days {
+		if !known[d] {
+			if known[strings.ToLower(d)] {
+				add("weekly."+d, "names the same day as another key")
+			} else {
+				add("weekly."+d, "must be a weekday name such as monday")
+			}
+			continue
+		}
+		for _, msg := range intervalErrors(h.Weekly[d]) {
+			add("weekly."+d+msg.Field, msg.Message)
+		}
+	}
+
+	if len(h.Exceptions) > maxExceptions {
+		add("exceptions", fmt.Sprintf("must have at most %d entries", maxExceptions))
+	}
+	seen := make(map[string]bool, len(h.Exceptions))
+	for i, e := range h.Exceptions {
+		field := fmt.Sprintf("exceptions[%d]", i)
+		if _, err := time.Parse(DateLayout, e.Date); err != nil {
+			add(field+".date", "must be a date like 2025-12-25")
+		} else if seen[e.Date] {
+			add(field+".date", "is listed twice")
+		}
+		seen[e.Date] = true
+		switch {
+		case e.Closed && len(e.Intervals) > 0:
+			add(field+".intervals", "must be empty when closed")
+		case !e.Closed && len(e.Intervals) == 0:
+			add(field+".intervals", "is required unless closed")
+		}
+		for _, msg := range intervalErrors(e.Intervals) {
+			add(field+".intervals"+msg.Field, msg.Message)
+		}
+		if len(e.Note) > 200 {
+			add(field+".note", "must be at most 200 characters")
+		}
+	}
+	return fields
+}
+
+// intervalErrors checks one day's intervals; fields are relative ("[0].open").
+func intervalErrors(ivs []Interval) []apperr.FieldError {
+	var fields []apperr.FieldError
+	type span struct{ start, end, i int }
+	spans := make([]span, 0, len(ivs))
+	for i, iv := range ivs {
+		open, okOpen := schedule.Clock(iv.Open, false)
+		close, okClose := schedule.Clock(iv.Close, true)
+		if !okOpen {
+			fields = append(fields, apperr.FieldError{Field: fmt.Sprintf("[%d].open", i), Message: "must be a time from 00:00 to 23:59"})
+		}
+		if !okClose {
+			fields = append(fields, apperr.FieldError{Field: fmt.Sprintf("[%d].close", i), Message: "must be a time from 00:00 to 24:00"})
+		}
+		if !okOpen || !okClose {
+			continue
+		}
+		if open == close {
+			fields = append(fields, apperr.FieldError{Field: fmt.Sprintf("[%d].close", i), Message: "must differ from open"})
+			continue
+		}
+		if close < open {
+			close += 24 * 60
+		}
+		spans = append(spans, span{open, close, i})
+	}
+	sort.Slice(spans, func(a, b int) bool { return spans[a].start < spans[b].start })
+	for k := 1; k < len(spans); k++ {
+		if spans[k].start < spans[k-1].end {
+			fields = append(fields, apperr.FieldError{Field: fmt.Sprintf("[%d]", spans[k].i), Message: "overlaps another interval"})
+		}
+	}
+	return fields
+}
+
+// OpenAt reports whether the restaurant is open at instant t. Intervals are
+// wall-clock times in the restaurant's timezone (see schedule.Active).
+func (h Hours) OpenAt(t time.Time) bool {
+	loc, err := schedule.Location(h.Timezone)
+	if err != nil {
+		return false
+	}
+	return schedule.Active(t, loc, func(day time.Time) []schedule.Range {
+		var out []schedule.Range
+		for _, iv := range h.on(day) {
+			if r, ok := schedule.ParseRange(iv.Open, iv.Close); ok {
+				out = append(out, r)
+			}
+		}
+		return out
+	})
+}
+
+// on returns the intervals for a local date: its exception if any,
+// otherwise the weekly hours.
+func (h Hours) on(day time.Time) []Interval {
+	date := day.Format(DateLayout)
+	for _, e := range h.Exceptions {
+		if e.Date == date {
+			if e.Closed {
+				return nil
+			}
+			return e.Intervals
+		}
+	}
+	return h.Weekly[Weekdays[day.Weekday()]]
+}
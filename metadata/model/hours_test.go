@@ -0,0 +1,59 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Day names are case-insensitive, so two keys for one day are rejected
+// rather than one silently replacing the other.
+func TestValidateRejectsDuplicateWeekdays(t *testing.T) {
+	tests := []struct {
+		name   string
+		weekly map[string][]Interval
+		want   []string // weekly field errors
+	}{
+		{
+			name:   "mixed case is folded",
+			weekly: map[string][]Interval{"Monday": {{Open: "09:00", Close: "17:00"}}, " TUESDAY ": {{Open: "09:00", Close: "17:00"}}},
+		},
+		{
+			name:   "same day twice",
+			weekly: map[string][]Interval{"Monday": {{Open: "09:00", Close: "17:00"}}, "monday": {{Open: "10:00", Close: "12:00"}}},
+			want:   []string{"hours.weekly.Monday"},
+		},
+		{
+			name:   "same day three ways",
+			weekly: map[string][]Interval{"Friday": nil, "FRIDAY": nil, "friday": nil},
+			want:   []string{"hours.weekly.FRIDAY", "hours.weekly.Friday"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := Metadata{
+				Name: "Nopa", CuisineType: "american", Address: "560 Divisadero St", City: "San Francisco",
+				Hours: &Hours{Timezone: "America/Los_Angeles", Weekly: tt.weekly},
+			}
+			x.Normalize()
+			var got []string
+			var e *apperr.Error
+			if err := x.Validate(); errors.As(err, &e) {
+				for _, f := range e.Fields {
+					got = append(got, f.Field)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("field errors = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("field errors = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,5 @@
 package model
 
-import "fmt"
-
 type Metadata struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
@@ -9,14 +7,24 @@ type Metadata struct {
 	PriceRange  string `json:"price_range"`
 	Address     string `json:"address"`
 	City        string `json:"city"`
+	// Nil until known: supplied by the client or geocoded from the address
+	Location *Location `json:"location,omitempty"`
+	Hours    *Hours    `json:"hours,omitempty"`
+	// Computed from Hours when a record is read; never stored
+	OpenNow *bool `json:"open_now,omitempty"`
+	// Bumped on every update; exposed as the ETag
+	Version int `json:"version"`
 }
 
-func (m Metadata) Validate() error {
-	if m.Name == "" {
-		return fmt.Errorf("name is required")
-	}
-	if m.Address == "" {
-		return fmt.Errorf("address is required")
-	}
-	return nil
-}
\ No newline at end of file
+// Accuracy of a geocoded location; empty when the client supplied it.
+const (
+	AccuracyAddress = "address"
+	AccuracyCity    = "city"
+)
+
+// Location is a WGS84 point in degrees.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  string  `json:"accuracy,omitempty"`
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Price ranges are stored in their canonical "$" form.
+const (
+	PriceInexpensive   = "$"
+	PriceModerate      = "$$"
+	PriceExpensive     = "$$$"
+	PriceVeryExpensive = "$$$$"
+)
+
+var priceAliases = map[string]string{
+	"$": PriceInexpensive, "1": PriceInexpensive, "cheap": PriceInexpensive, "inexpensive": PriceInexpensive,
+	"$$": PriceModerate, "2": PriceModerate, "moderate": PriceModerate,
+	"$$$": PriceExpensive, "3": PriceExpensive, "expensive": PriceExpensive,
+	"$$$$": PriceVeryExpensive, "4": PriceVeryExpensive, "very expensive": PriceVeryExpensive,
+}
+
+// NormalizePriceRange maps the accepted spellings of a price range ("2",
+// "moderate", "$$") onto the canonical form. ok is false for unknown values.
+func NormalizePriceRange(s string) (string, bool) {
+	p, ok := priceAliases[strings.ToLower(strings.TrimSpace(s))]
+	return p, ok
+}
+
+// Normalize trims string fields and canonicalizes the price range. Unknown
+// price ranges are left as-is so Validate can report them.
+func (m *Metadata) Normalize() {
+	m.Name = strings.TrimSpace(m.Name)
+	m.CuisineType = strings.TrimSpace(m.CuisineType)
+	m.Address = strings.Join(strings.Fields(m.Address), " ")
+	m.City = strings.Join(strings.Fields(m.City), " ")
+	m.PriceRange = strings.TrimSpace(m.PriceRange)
+	if p, ok := NormalizePriceRange(m.PriceRange); ok {
+		m.PriceRange = p
+	}
+	if m.Hours != nil {
+		m.Hours.normalize()
+	}
+	m.OpenNow = nil
+}
+
+// Validate checks every field and reports all violations at once.
+func (m Metadata) Validate() error {
+	var fields []apperr.FieldError
+	add := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: field, Message: msg})
+	}
+
+	switch {
+	case m.Name == "":
+		add("name", "is required")
+	case utf8.RuneCountInString(m.Name) > 100:
+		add("name", "must be at most 100 characters")
+	}
+
+	switch {
+	case m.CuisineType == "":
+		add("cuisine_type", "is required")
+	case utf8.RuneCountInString(m.CuisineType) > 50:
+		add("cuisine_type", "must be at most 50 characters")
+	}
+
+	switch n := utf8.RuneCountInString(m.Address); {
+	case n == 0:
+		add("address", "is required")
+	case n < 5 || n > 200:
+		add("address", "must be between 5 and 200 characters")
+	case !validAddress(m.Address):
+		add("address", "may only contain letters, digits, spaces and , . # / ' -")
+	}
+
+	if m.City != "" {
+		switch n := utf8.RuneCountInString(m.City); {
+		case n < 2 || n > 100:
+			add("city", "must be between 2 and 100 characters")
+		case !validCity(m.City):
+			add("city", "may only contain letters, spaces and . ' -")
+		}
+	}
+
+	if m.PriceRange != "" {
+		if _, ok := NormalizePriceRange(m.PriceRange); !ok {
+			add("price_range", "must be one of $, $$, $$$, $$$$")
+		}
+	}
+
+	if l := m.Location; l != nil {
+		if !ValidLatitude(l.Latitude) {
+			add("location.latitude", "must be between -90 and 90")
+		}
+		if !ValidLongitude(l.Longitude) {
+			add("location.longitude", "must be between -180 and 180")
+		}
+	}
+
+	if m.Hours != nil {
+		fields = append(fields, m.Hours.fieldErrors()...)
+	}
+
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
+
+// ValidLatitude and ValidLongitude reject out-of-range values and NaN.
+func ValidLatitude(v float64) bool  { return v >= -90 && v <= 90 }
+func ValidLongitude(v float64) bool { return v >= -180 && v <= 180 }
+
+func validAddress(s string) bool {
+	hasLetter := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r), r == ' ', strings.ContainsRune(",.#/'-", r):
+		default:
+			return false
+		}
+	}
+	return hasLetter
+}
+
+func validCity(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && r != ' ' && !strings.ContainsRune(".'-", r) {
+			return false
+		}
+	}
+	return true
+}
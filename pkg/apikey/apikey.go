@@ -0,0 +1,64 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Header carries a partner API key.
+const Header = "X-API-Key"
+
+// Scopes a key can be granted.
+const (
+	ScopeMetadataRead       = "metadata:read"
+	ScopeReviewsRead        = "reviews:read"
+	ScopeReservationsCreate = "reservations:create"
+)
+
+var Scopes = []string{ScopeMetadataRead, ScopeReviewsRead, ScopeReservationsCreate}
+
+const prefix = "otk_"
+
+// Generate returns a new key id and the plaintext key "otk_<id>_<secret>".
+// Only the hash of the plaintext is ever stored.
+func Generate() (id, plaintext string, err error) {
+	idb := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idb); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(idb)
+	return id, prefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// ParseID extracts the key id from a plaintext key.
+func ParseID(plaintext string) (string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, prefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// Hash returns the stored form of a plaintext key. Keys carry 256 bits of
+// randomness, so a fast hash is sufficient.
+func Hash(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches compares a plaintext key with a stored hash in constant time.
+func Matches(plaintext, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(plaintext)), []byte(hash)) == 1
+}
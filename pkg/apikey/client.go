@@ -0,0 +1,131 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/discovery"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// CheckResult is returned by the apikey service for an accepted key; the
+// call also counts against the key's daily quota.
+type CheckResult struct {
+	KeyID      string    `json:"key_id"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	DailyQuota int       `json:"daily_quota"`
+	Remaining  int       `json:"remaining"`
+	ResetsAt   time.Time `json:"resets_at"`
+}
+
+// Client checks keys against the apikey service discovered via Consul.
+type Client struct {
+	client   *http.Client
+	resolver *discovery.Resolver
+}
+
+func NewClient() *Client {
+	tr := &http.Transport{
+		DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		IdleConnTimeout:       30 * time.Second,
+		MaxIdleConns:          10,
+	}
+	consul := &http.Client{Transport: tracing.Transport(tr)}
+	client := &http.Client{Transport: auth.ServiceTransport(consul.Transport)}
+	return &Client{client: client, resolver: discovery.NewResolver("apikey", consul)}
+}
+
+// Check verifies key for scope and consumes one unit of its quota.
+func (c *Client) Check(ctx context.Context, key, scope string) (CheckResult, error) {
+	if _, has := ctx.Deadline(); !has {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+	base, err := c.resolver.BaseURL(ctx)
+	if err != nil {
+		return CheckResult{}, apperr.Unavailable(err, "apikey service unavailable")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/apikeys/check?scope="+url.QueryEscape(scope), nil)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	req.Header.Set(Header, key)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return CheckResult{}, apperr.Unavailable(err, "apikey service unavailable")
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, 1<<20)
+	if resp.StatusCode != http.StatusOK {
+		// Pass the service's problem detail through under our own status
+		var p problem.Problem
+		_ = json.NewDecoder(body).Decode(&p)
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			return CheckResult{}, apperr.Unauthenticated("%s", p.Detail)
+		case http.StatusForbidden:
+			return CheckResult{}, apperr.Forbidden("%s", p.Detail)
+		case http.StatusTooManyRequests:
+			return CheckResult{}, apperr.RateLimited("%s", p.Detail)
+		default:
+			return CheckResult{}, apperr.Unavailable(fmt.Errorf("apikey check -> %d", resp.StatusCode), "apikey service unavailable")
+		}
+	}
+	var out CheckResult
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
+		return CheckResult{}, apperr.Unavailable(err, "apikey service unavailable")
+	}
+	return out, nil
+}
+
+// Middleware authenticates requests carrying an X-API-Key header. routes maps
+// "METHOD /path" to the scope it needs; keys are refused on other routes.
+// Requests without the header pass through untouched: the read routes stay
+// public, as they were before keys existed, and a key gives a partner its
+// own identity, quota and scopes rather than gating access. Each key is
+// checked remotely, so run ratelimit.PreAuth in front of it.
+func (c *Client) Middleware(routes map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Malformed keys never reach the apikey service
+			if _, ok := ParseID(key); !ok {
+				problem.Write(w, r, apperr.Unauthenticated("invalid api key"))
+				return
+			}
+			scope, ok := routes[r.Method+" "+r.URL.Path]
+			if !ok {
+				problem.Write(w, r, apperr.Forbidden("API keys cannot access %s %s", r.Method, r.URL.Path))
+				return
+			}
+			res, err := c.Check(r.Context(), key, scope)
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+			w.Header().Set("X-API-Quota-Limit", strconv.Itoa(res.DailyQuota))
+			w.Header().Set("X-API-Quota-Remaining", strconv.Itoa(res.Remaining))
+			p := auth.Principal{Subject: "apikey:" + res.KeyID, Scopes: res.Scopes}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kind classifies domain errors so handlers can map them to HTTP statuses
+// without knowing which controller produced them.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindValidation
+	KindConflict
+	KindUnavailable
+	KindUnauthenticated
+	KindForbidden
+	KindRateLimited
+	KindPreconditionFailed
+	KindPreconditionRequired
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not-found"
+	case KindValidation:
+		return "validation"
+	case KindConflict:
+		return "conflict"
+	case KindUnavailable:
+		return "unavailable"
+	case KindUnauthenticated:
+		return "unauthenticated"
+	case KindForbidden:
+		return "forbidden"
+	case KindRateLimited:
+		return "rate-limited"
+	case KindPreconditionFailed:
+		return "precondition-failed"
+	case KindPreconditionRequired:
+		return "precondition-required"
+	default:
+		return "internal"
+	}
+}
+
+// FieldError describes a single invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is the domain error returned by controllers.
+type Error struct {
+	Kind   Kind
+	Msg    string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := e.Msg
+	if len(e.Fields) > 0 {
+		parts := make([]string, 0, len(e.Fields))
+		for _, f := range e.Fields {
+			parts = append(parts, f.Field+": "+f.Message)
+		}
+		msg += " (" + strings.Join(parts, "; ") + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func NotFound(format string, args ...any) *Error {
+	return &Error{Kind: KindNotFound, Msg: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...any) *Error {
+	return &Error{Kind: KindConflict, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Unauthenticated reports a request that needs a caller identity but has none.
+func Unauthenticated(format string, args ...any) *Error {
+	return &Error{Kind: KindUnauthenticated, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden reports an authenticated caller lacking permission.
+func Forbidden(format string, args ...any) *Error {
+	return &Error{Kind: KindForbidden, Msg: fmt.Sprintf(format, args...)}
+}
+
+// RateLimited reports a caller that used up its request allowance.
+func RateLimited(format string, args ...any) *Error {
+	return &Error{Kind: KindRateLimited, Msg: fmt.Sprintf(format, args...)}
+}
+
+// PreconditionFailed reports a conditional write against a stale version.
+func PreconditionFailed(format string, args ...any) *Error {
+	return &Error{Kind: KindPreconditionFailed, Msg: fmt.Sprintf(format, args...)}
+}
+
+// PreconditionRequired reports a write that must be conditional but isn't.
+func PreconditionRequired(format string, args ...any) *Error {
+	return &Error{Kind: KindPreconditionRequired, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Unavailable reports that a dependency (Consul, another service) could not
+// be reached; err is the underlying cause.
+func Unavailable(err error, format string, args ...any) *Error {
+	return &Error{Kind: KindUnavailable, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Validation reports one or more invalid fields.
+func Validation(fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Msg: "validation failed", Fields: fields}
+}
+
+// Field is shorthand for a single-field validation error.
+func Field(field, message string) *Error {
+	return Validation(FieldError{Field: field, Message: message})
+}
+
+// KindOf returns the Kind of the first *Error in err's chain, or
+// KindInternal if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// Is reports whether err carries the given kind.
+func Is(err error, k Kind) bool {
+	return err != nil && KindOf(err) == k
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
+)
+
+// Claims are the JWT claims issued for callers of the services.
+type Claims struct {
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Verifier validates bearer tokens against a KeySet, and service tokens
+// (kid ServiceKeyID) against the service secret.
+type Verifier struct {
+	keys    *KeySet
+	service []byte
+	parser  *jwt.Parser
+}
+
+// NewVerifier accepts HS256 and RS256 tokens signed by a key in ks. issuer
+// and audience are checked when non-empty.
+func NewVerifier(ks *KeySet, issuer, audience string) *Verifier {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	return &Verifier{keys: ks, parser: jwt.NewParser(opts...)}
+}
+
+// WithServiceSecret makes v accept service tokens signed with secret.
+// Without it every token claiming a service identity is rejected.
+func (v *Verifier) WithServiceSecret(secret []byte) *Verifier {
+	v.service = secret
+	return v
+}
+
+// VerifierFromEnv builds a Verifier from JWT_HS256_SECRET_FILE,
+// JWT_RS256_PUBLIC_KEY_FILE and JWT_JWKS_FILE (any combination), plus the
+// optional JWT_ISSUER, JWT_AUDIENCE and SERVICE_TOKEN_SECRET_FILE.
+func VerifierFromEnv() (*Verifier, error) {
+	ks := NewKeySet()
+	if p := os.Getenv("JWT_HS256_SECRET_FILE"); p != "" {
+		if err := ks.AddHMACFile("", p); err != nil {
+			return nil, err
+		}
+	}
+	if p := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); p != "" {
+		if err := ks.AddRSAPublicKeyFile("", p); err != nil {
+			return nil, err
+		}
+	}
+	if p := os.Getenv("JWT_JWKS_FILE"); p != "" {
+		if err := ks.AddJWKSFile(p); err != nil {
+			return nil, err
+		}
+	}
+	if ks.Empty() {
+		return nil, errors.New("no JWT keys configured (set JWT_HS256_SECRET_FILE, JWT_RS256_PUBLIC_KEY_FILE or JWT_JWKS_FILE)")
+	}
+	secret, err := readServiceSecret()
+	if err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		for _, k := range ks.hmac {
+			if bytes.Equal(k, secret) {
+				return nil, errServiceKey
+			}
+		}
+	}
+	return NewVerifier(ks, os.Getenv("JWT_ISSUER"), os.Getenv("JWT_AUDIENCE")).WithServiceSecret(secret), nil
+}
+
+// Verify parses and validates a raw token. Service identities ("service:"
+// subjects, the service role) are only accepted from service tokens, and
+// service tokens carry nothing else.
+func (v *Verifier) Verify(raw string) (Principal, error) {
+	var (
+		c         Claims
+		byService bool
+	)
+	keyfunc := func(t *jwt.Token) (any, error) {
+		if kid, _ := t.Header["kid"].(string); kid == ServiceKeyID {
+			if v.service == nil || t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+				return nil, errors.New("service tokens are not accepted")
+			}
+			byService = true
+			return v.service, nil
+		}
+		return v.keys.keyfunc(t)
+	}
+	if _, err := v.parser.ParseWithClaims(raw, &c, keyfunc); err != nil {
+		return Principal{}, err
+	}
+	if c.Subject == "" {
+		return Principal{}, errors.New("token has no subject")
+	}
+	switch {
+	case byService && (!strings.HasPrefix(c.Subject, "service:") || !slices.Equal(c.Roles, []string{RoleService})):
+		return Principal{}, errors.New("service token must have a service subject and only the service role")
+	case !byService && isServiceClaim(c):
+		return Principal{}, errors.New("service identity in a token not signed with the service key")
+	}
+	return Principal{Subject: c.Subject, Roles: c.Roles}, nil
+}
+
+// Middleware authenticates bearer tokens. Mutating requests (anything but
+// GET, HEAD and OPTIONS) must carry a valid token; reads are allowed
+// anonymously but still get a principal when a valid token is sent.
+// Requests already authenticated by an earlier middleware (API keys) pass
+// through.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		raw, hasToken := bearerToken(r)
+		if !hasToken {
+			if isMutating(r.Method) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				problem.Error(w, r, http.StatusUnauthorized, "bearer token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, err := v.Verify(raw)
+		if err != nil {
+			log.Printf("auth: rejected token for %s %s: %v", r.Method, r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Error(w, r, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyKeepsServiceIdentitiesToTheServiceKey(t *testing.T) {
+	userSecret := []byte(strings.Repeat("u", 32))
+	serviceSecret := []byte(strings.Repeat("s", 32))
+	ks := NewKeySet()
+	ks.hmac[""] = userSecret
+	v := NewVerifier(ks, "", "").WithServiceSecret(serviceSecret)
+
+	sign := func(secret []byte, kid, sub string, roles ...string) string {
+		t.Helper()
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			Roles: roles,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   sub,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		})
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"user token", sign(userSecret, "", "alice", "diner"), true},
+		{"service token", sign(serviceSecret, ServiceKeyID, "service:review", RoleService), true},
+		{"service subject under user key", sign(userSecret, "", "service:review", RoleService), false},
+		{"service role under user key", sign(userSecret, "", "alice", RoleService), false},
+		{"service kid signed with user key", sign(userSecret, ServiceKeyID, "service:review", RoleService), false},
+		{"user subject under service key", sign(serviceSecret, ServiceKeyID, "alice", RoleService), false},
+		{"extra role under service key", sign(serviceSecret, ServiceKeyID, "service:review", RoleService, "admin"), false},
+		{"service key without its kid", sign(serviceSecret, "", "service:review", RoleService), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.Verify(tc.token)
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("Verify ok = %v, want %v (err %v)", ok, tc.ok, err)
+			}
+		})
+	}
+
+	t.Run("no service secret", func(t *testing.T) {
+		v := NewVerifier(ks, "", "")
+		if _, err := v.Verify(sign(serviceSecret, ServiceKeyID, "service:review", RoleService)); err == nil {
+			t.Fatal("service token accepted without a service secret")
+		}
+	})
+}
+
+func TestServiceTokensVerify(t *testing.T) {
+	secret := []byte(strings.Repeat("s", 32))
+	s := &serviceTokens{subject: "service:restaurant", secret: secret}
+	raw, err := s.get(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewVerifier(NewKeySet(), "", "").WithServiceSecret(secret).Verify(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "service:restaurant" || !p.IsService() {
+		t.Fatalf("principal = %+v", p)
+	}
+}
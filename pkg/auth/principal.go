@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string   `json:"sub"`
+	Roles   []string `json:"roles,omitempty"`
+	// Scopes are set for partner API keys instead of roles
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HasScope reports whether p is an API key granted scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal stored by the middleware, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// Static attaches p to every request that carries no principal yet. It is
+// meant for local runs with token checks switched off.
+func Static(p Principal) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := FromContext(r.Context()); !ok {
+				r = r.WithContext(WithPrincipal(r.Context(), p))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
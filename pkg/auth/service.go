@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RoleService marks tokens the services mint for calls to each other. It
+// grants no permissions; it lets the rate limiter give internal callers
+// their own quota instead of sharing one bucket per IP.
+const RoleService = "service"
+
+// IsService reports whether p is another service rather than a user.
+func (p Principal) IsService() bool {
+	return slices.Contains(p.Roles, RoleService)
+}
+
+const serviceTokenTTL = 5 * time.Minute
+
+// ServiceKeyID is the kid header of service tokens. The Verifier checks
+// tokens carrying it against the service secret only.
+const ServiceKeyID = "service"
+
+// isServiceClaim reports whether c claims to come from a service, by
+// subject or by role.
+func isServiceClaim(c Claims) bool {
+	return strings.HasPrefix(c.Subject, "service:") || slices.Contains(c.Roles, RoleService)
+}
+
+// readServiceSecret loads the SERVICE_TOKEN_SECRET_FILE secret; nil when
+// the variable is unset.
+func readServiceSecret() ([]byte, error) {
+	path := os.Getenv("SERVICE_TOKEN_SECRET_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read service token secret: %w", err)
+	}
+	if len(b) < 32 {
+		return nil, fmt.Errorf("service token secret in %s is shorter than 32 bytes", path)
+	}
+	return b, nil
+}
+
+var errServiceKey = errors.New("service token secret must differ from the user token secrets")
+
+// serviceTokens mints short-lived HS256 tokens for this service.
+type serviceTokens struct {
+	subject  string
+	issuer   string
+	audience string
+	secret   []byte
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var service struct {
+	mu     sync.RWMutex
+	tokens *serviceTokens
+}
+
+// InitService makes ServiceTransport sign outgoing calls as "service:<name>"
+// with the SERVICE_TOKEN_SECRET_FILE secret (and JWT_ISSUER / JWT_AUDIENCE
+// when set). The secret is kept apart from the user token keys so that
+// holding those can't mint service identities. Without it calls go out
+// unauthenticated and are limited like anonymous clients.
+func InitService(name string) error {
+	secret, err := readServiceSecret()
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		log.Println("SERVICE_TOKEN_SECRET_FILE not set: calls to other services are unauthenticated")
+		return nil
+	}
+	service.mu.Lock()
+	service.tokens = &serviceTokens{
+		subject:  "service:" + name,
+		issuer:   os.Getenv("JWT_ISSUER"),
+		audience: os.Getenv("JWT_AUDIENCE"),
+		secret:   secret,
+	}
+	service.mu.Unlock()
+	return nil
+}
+
+// get returns a cached token, minting a new one when it has less than a
+// minute left.
+func (s *serviceTokens) get(now time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && now.Add(time.Minute).Before(s.expires) {
+		return s.token, nil
+	}
+	claims := Claims{
+		Roles: []string{RoleService},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   s.subject,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(serviceTokenTTL)),
+		},
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = ServiceKeyID
+	signed, err := token.SignedString(s.secret)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expires = signed, now.Add(serviceTokenTTL)
+	return signed, nil
+}
+
+// ServiceTransport adds this service's bearer token (see InitService) to
+// requests that carry no Authorization header. Wrap only clients that call
+// the other services, not Consul.
+func ServiceTransport(rt http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		service.mu.RLock()
+		s := service.tokens
+		service.mu.RUnlock()
+		if s == nil || r.Header.Get("Authorization") != "" {
+			return rt.RoundTrip(r)
+		}
+		token, err := s.get(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "Bearer "+token)
+		return rt.RoundTrip(r)
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
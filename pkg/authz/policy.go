@@ -0,0 +1,88 @@
+package authz
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+)
+
+type Role string
+
+const (
+	RoleDiner     Role = "diner"
+	RoleOwner     Role = "owner"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+type Permission string
+
+const (
+	MetadataCreate Permission = "metadata:create"
+	MetadataUpdate Permission = "metadata:update"
+
+	RestaurantCreate Permission = "restaurant:create"
+	// RestaurantManage covers changes to a restaurant; non-admins must also
+	// own the record (see AuthorizeOwner).
+	RestaurantManage Permission = "restaurant:manage"
+
+	// ReservationCreate lets diners book tables for themselves; partners
+	// book with the apikey.ScopeReservationsCreate scope instead.
+	ReservationCreate Permission = "reservation:create"
+
+	ReviewCreate   Permission = "review:create"
+	ReviewVote     Permission = "review:vote"
+	ReviewModerate Permission = "review:moderate"
+
+	// APIKeyManage covers issuing, listing and revoking partner API keys
+	// (admin only).
+	APIKeyManage Permission = "apikey:manage"
+)
+
+// policy is the role → permission table. Admins are granted everything.
+var policy = map[Role][]Permission{
+	RoleDiner:     {ReviewCreate, ReviewVote, ReservationCreate},
+	RoleOwner:     {RestaurantCreate, RestaurantManage},
+	RoleModerator: {ReviewModerate},
+}
+
+// Can reports whether any of roles grants perm.
+func Can(roles []string, perm Permission) bool {
+	for _, r := range roles {
+		if Role(r) == RoleAdmin || slices.Contains(policy[Role(r)], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks that the caller in ctx holds perm.
+func Authorize(ctx context.Context, perm Permission) (auth.Principal, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.Principal{}, apperr.Unauthenticated("authentication required")
+	}
+	if !Can(p.Roles, perm) {
+		return p, apperr.Forbidden("missing permission %s", perm)
+	}
+	return p, nil
+}
+
+// AuthorizeOwner checks perm and, unless the caller is an admin, that the
+// caller is ownerID.
+func AuthorizeOwner(ctx context.Context, perm Permission, ownerID string) (auth.Principal, error) {
+	p, err := Authorize(ctx, perm)
+	if err != nil {
+		return p, err
+	}
+	if !IsAdmin(p) && p.Subject != ownerID {
+		return p, apperr.Forbidden("not the owner of this resource")
+	}
+	return p, nil
+}
+
+func IsAdmin(p auth.Principal) bool {
+	return slices.Contains(p.Roles, string(RoleAdmin))
+}
@@ -0,0 +1,112 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// Resolver finds a healthy instance of one service in Consul and caches the
+// answer for 30s.
+type Resolver struct {
+	consulAddr string
+	service    string
+	client     *http.Client
+	observe    func(hit bool, err error)
+
+	mu        sync.RWMutex
+	cachedURL string
+	expires   time.Time
+}
+
+// NewResolver uses CONSUL_HTTP_ADDR (default http://consul:8500).
+func NewResolver(service string, client *http.Client) *Resolver {
+	consul := os.Getenv("CONSUL_HTTP_ADDR")
+	if consul == "" {
+		consul = "http://consul:8500"
+	}
+	return &Resolver{consulAddr: consul, service: service, client: client}
+}
+
+// Observe registers fn to be told whether each BaseURL call was answered
+// from cache and, for Consul lookups, how the lookup ended. Callers use it
+// for their own metrics; call it before the resolver is shared.
+func (r *Resolver) Observe(fn func(hit bool, err error)) *Resolver {
+	r.observe = fn
+	return r
+}
+
+// BaseURL returns http://host:port of a passing instance.
+func (r *Resolver) BaseURL(ctx context.Context) (u string, err error) {
+	ctx, span := tracing.Start(ctx, "pkg/discovery", "consul.resolve", attribute.String("service.name", r.service))
+	defer func() { tracing.End(span, err) }()
+
+	r.mu.RLock()
+	if time.Now().Before(r.expires) && r.cachedURL != "" {
+		u := r.cachedURL
+		r.mu.RUnlock()
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		if r.observe != nil {
+			r.observe(true, nil)
+		}
+		return u, nil
+	}
+	r.mu.RUnlock()
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	if r.observe != nil {
+		defer func() { r.observe(false, err) }()
+	}
+
+	type svc struct {
+		Service struct {
+			Address string
+			Port    int
+		}
+		Node struct {
+			Address string
+		}
+	}
+	q := r.consulAddr + "/v1/health/service/" + url.PathEscape(r.service) + "?passing=true"
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, q, nil)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("consul query failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("consul query status: %d", resp.StatusCode)
+	}
+
+	var arr []svc
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&arr); err != nil {
+		return "", fmt.Errorf("consul decode failed: %w", err)
+	}
+	if len(arr) == 0 {
+		return "", fmt.Errorf("no healthy %s instances in consul", r.service)
+	}
+
+	addr := arr[0].Service.Address
+	if addr == "" {
+		addr = arr[0].Node.Address
+	}
+	if addr == "" || arr[0].Service.Port == 0 {
+		return "", fmt.Errorf("consul result missing address/port")
+	}
+
+	u = fmt.Sprintf("http://%s:%d", addr, arr[0].Service.Port)
+	r.mu.Lock()
+	r.cachedURL = u
+	r.expires = time.Now().Add(30 * time.Second)
+	r.mu.Unlock()
+	return u, nil
+}
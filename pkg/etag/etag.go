@@ -0,0 +1,110 @@
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Format returns the strong ETag for a record version. Tags map 1:1 to the
+// version field, so If-Match/If-None-Match compare versions.
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Derive returns the ETag for a representation built from the record at
+// version plus other inputs, such as an embedded record's version or a value
+// computed at read time. The record version stays the leading component, so
+// the tag still works as an If-Match precondition for writes to the record.
+func Derive(version int, inputs ...int) string {
+	var b strings.Builder
+	b.WriteString(`"`)
+	b.WriteString(strconv.Itoa(version))
+	for _, in := range inputs {
+		b.WriteByte('.')
+		b.WriteString(strconv.Itoa(in))
+	}
+	b.WriteString(`"`)
+	return b.String()
+}
+
+// Precondition is a parsed If-Match header.
+type Precondition struct {
+	Any      bool
+	Versions []int
+}
+
+// Allows reports whether a record at version satisfies the precondition.
+func (p Precondition) Allows(version int) bool {
+	if p.Any {
+		return true
+	}
+	for _, v := range p.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch parses the If-Match header of a write. A missing header is a
+// PreconditionRequired error; weak tags never match (RFC 9110 strong
+// comparison).
+func IfMatch(r *http.Request) (Precondition, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" {
+		return Precondition{}, apperr.PreconditionRequired("If-Match is required; send the ETag from the last read")
+	}
+	if h == "*" {
+		return Precondition{Any: true}, nil
+	}
+	var p Precondition
+	for _, tag := range strings.Split(h, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if v, ok := parse(tag); ok {
+			p.Versions = append(p.Versions, v)
+		}
+	}
+	return p, nil
+}
+
+// NotModified reports whether If-None-Match matches the record version, in
+// which case a read should answer 304. Weak tags compare equal to strong ones.
+func NotModified(r *http.Request, version int) bool {
+	return NoneMatch(r, Format(version))
+}
+
+// NoneMatch is NotModified for a tag from Derive.
+func NoneMatch(r *http.Request, etag string) bool {
+	h := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if h == "" {
+		return false
+	}
+	if h == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(h, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parse returns the record version of a tag from Format or Derive.
+func parse(tag string) (int, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	tag = tag[1 : len(tag)-1]
+	if i := strings.IndexByte(tag, '.'); i >= 0 {
+		tag = tag[:i]
+	}
+	v, err := strconv.Atoi(tag)
+	return v, err == nil
+}
@@ -0,0 +1,216 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLen = 255
+	// Bodies are hashed as they are read and set aside for the handler: in
+	// memory up to memBodyLen, in a temp file beyond. maxBodyLen only stops
+	// runaway bodies; it sits above every route's own limit (photo uploads)
+	// so those still decide what is too large.
+	memBodyLen = 1 << 20
+	maxBodyLen = 256 << 20
+)
+
+// TTLFromEnv reads IDEMPOTENCY_TTL (a Go duration), defaulting to 24h.
+func TTLFromEnv() time.Duration {
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("idempotency: invalid IDEMPOTENCY_TTL %q, using 24h", v)
+	}
+	return 24 * time.Hour
+}
+
+// Middleware makes POSTs carrying an Idempotency-Key safe to retry: the first
+// response is stored for ttl and replayed for later requests with the same
+// key and body. Reusing a key with a different request is rejected with 422,
+// and a retry racing the first request gets 409. Keys are scoped to the
+// caller, so it must run after the auth middleware. 5xx responses are not
+// stored, so a retry runs the request again.
+func Middleware(store Store, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLen {
+				problem.Write(w, r, apperr.Field(Header, "must be at most 255 characters"))
+				return
+			}
+
+			h := sha256.New()
+			io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+			body, err := spool(http.MaxBytesReader(w, r.Body, maxBodyLen), h)
+			r.Body.Close()
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					problem.Error(w, r, http.StatusRequestEntityTooLarge, "body too large for an Idempotency-Key request")
+					return
+				}
+				problem.Error(w, r, http.StatusBadRequest, "invalid body")
+				return
+			}
+			defer body.Close()
+			r.Body = body
+
+			scoped := caller(r) + "|" + key
+			fp := hex.EncodeToString(h.Sum(nil))
+			rec, ok, err := store.Begin(r.Context(), scoped, fp, ttl)
+			if err != nil {
+				log.Printf("idempotency: store error, running request without a key: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !ok {
+				switch {
+				case rec.Fingerprint != fp:
+					problem.Error(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+				case !rec.Done:
+					problem.Write(w, r, apperr.Conflict("a request with this Idempotency-Key is still in progress"))
+				default:
+					replay(w, rec.Response)
+				}
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w, before: w.Header().Clone(), status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					_ = store.Abort(r.Context(), scoped)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+
+			if rw.status >= 500 {
+				return
+			}
+			if err := store.Complete(r.Context(), scoped, rw.response()); err != nil {
+				log.Printf("idempotency: store error: %v", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, resp Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = slices.Clone(v)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.Body)))
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// ----- Support function -----
+
+func caller(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "sub:" + p.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// spool reads body through h and returns a copy to hand on, kept in memory
+// up to memBodyLen and in a temp file (removed on Close) beyond that.
+func spool(body io.Reader, h hash.Hash) (io.ReadCloser, error) {
+	var head bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(h, &head), io.LimitReader(body, memBodyLen+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= memBodyLen {
+		return io.NopCloser(&head), nil
+	}
+	f, err := os.CreateTemp("", "idempotency-body-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp := &tempBody{f}
+	if _, err := f.Write(head.Bytes()); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(io.MultiWriter(h, f), body); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// tempBody is a spooled body that deletes its file when closed. Closing
+// twice (by the server and by the middleware) is harmless.
+type tempBody struct{ *os.File }
+
+func (b *tempBody) Close() error {
+	b.File.Close()
+	return os.Remove(b.Name())
+}
+
+// recorder passes the response through and keeps a copy of it. Only headers
+// set by the handler itself are kept, not those of earlier middleware
+// (rate limit, quota).
+type recorder struct {
+	http.ResponseWriter
+	before http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recorder) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recorder) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+func (rw *recorder) response() Response {
+	h := make(http.Header)
+	for k, v := range rw.Header() {
+		if !slices.Equal(rw.before[k], v) {
+			h[k] = slices.Clone(v)
+		}
+	}
+	return Response{Status: rw.status, Header: h, Body: bytes.Clone(rw.body.Bytes())}
+}
@@ -0,0 +1,136 @@
+package idempotency
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Many concurrent retries of one request must run the handler once; the
+// rest either replay its response or see it still in flight.
+func TestMiddlewareConcurrentSameKey(t *testing.T) {
+	const workers = 64
+	var runs atomic.Int32
+	release := make(chan struct{})
+	h := Middleware(NewMemory(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs.Add(1)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	codes := make(map[int]int)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			req := httptest.NewRequest(http.MethodPost, "/reviews", strings.NewReader(`{"rating":5}`))
+			req.Header.Set(Header, "same-key")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			mu.Lock()
+			codes[rec.Code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	// Let every request reach the store before the first one finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+	if codes[http.StatusCreated]+codes[http.StatusConflict] != workers {
+		t.Fatalf("unexpected status codes: %v", codes)
+	}
+
+	// Once done, a retry replays the stored response
+	req := httptest.NewRequest(http.MethodPost, "/reviews", strings.NewReader(`{"rating":5}`))
+	req.Header.Set(Header, "same-key")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "true" || rec.Body.String() != `{"id":1}` {
+		t.Fatalf("replay = %d %q %q", rec.Code, rec.Header().Get(ReplayedHeader), rec.Body.String())
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("handler ran %d times after replay, want 1", n)
+	}
+}
+
+// Distinct keys under contention each run exactly once.
+func TestMiddlewareConcurrentManyKeys(t *testing.T) {
+	const keys, retries = 32, 8
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	h := Middleware(NewMemory(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		runs[r.Header.Get(Header)]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	var wg sync.WaitGroup
+	for k := 0; k < keys; k++ {
+		for i := 0; i < retries; i++ {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodPost, "/menus", strings.NewReader("{}"))
+				req.Header.Set(Header, "key-"+string(rune('a'+k)))
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}(k)
+		}
+	}
+	wg.Wait()
+
+	if len(runs) != keys {
+		t.Fatalf("%d keys ran, want %d", len(runs), keys)
+	}
+	for k, n := range runs {
+		if n != 1 {
+			t.Errorf("key %s ran %d times, want 1", k, n)
+		}
+	}
+}
+
+// Bodies past the in-memory limit are spooled to disk: the handler still
+// sees them whole, and a retry with one byte changed is told apart.
+func TestMiddlewareSpoolsLargeBodies(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 3*memBodyLen)
+	var got []byte
+	h := Middleware(NewMemory(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	send := func(b []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/photos", bytes.NewReader(b))
+		req.Header.Set(Header, "upload")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(body); rec.Code != http.StatusCreated || !bytes.Equal(got, body) {
+		t.Fatalf("first = %d, handler read %d of %d bytes", rec.Code, len(got), len(body))
+	}
+	if rec := send(body); rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "true" {
+		t.Fatalf("retry = %d replayed=%q", rec.Code, rec.Header().Get(ReplayedHeader))
+	}
+	changed := bytes.Clone(body)
+	changed[len(changed)-1] = 'y'
+	if rec := send(changed); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("changed body = %d, want 422", rec.Code)
+	}
+}
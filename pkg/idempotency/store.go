@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Response is a stored response replayed for retries.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is the state of one key.
+type Record struct {
+	Fingerprint string
+	// Done is false while the first request is still running.
+	Done     bool
+	Response Response
+}
+
+// Store keeps records for their TTL. A shared implementation makes keys
+// hold across every instance of a service.
+type Store interface {
+	// Begin reserves key for fingerprint. When the key already exists it
+	// returns the existing record and ok=false.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (rec Record, ok bool, err error)
+	// Complete stores the response for a reserved key.
+	Complete(ctx context.Context, key string, resp Response) error
+	// Abort releases a reserved key so a retry runs again.
+	Abort(ctx context.Context, key string) error
+}
+
+type entry struct {
+	rec     Record
+	expires time.Time
+}
+
+// Memory is a Store local to one process.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	sweep   time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]*entry)}
+}
+
+func (m *Memory) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.sweepExpired(now)
+
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		return e.rec, false, nil
+	}
+	m.entries[key] = &entry{rec: Record{Fingerprint: fingerprint}, expires: now.Add(ttl)}
+	return Record{Fingerprint: fingerprint}, true, nil
+}
+
+func (m *Memory) Complete(_ context.Context, key string, resp Response) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.rec.Done = true
+		e.rec.Response = resp
+	}
+	return nil
+}
+
+func (m *Memory) Abort(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// sweepExpired drops expired records at most once a minute.
+func (m *Memory) sweepExpired(now time.Time) {
+	if now.Sub(m.sweep) < time.Minute {
+		return
+	}
+	m.sweep = now
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+}
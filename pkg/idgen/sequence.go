@@ -0,0 +1,24 @@
+package idgen
+
+import "sync/atomic"
+
+// Sequence hands out increasing integer IDs and is safe for concurrent use.
+// The zero value starts at 1.
+type Sequence struct {
+	last atomic.Int64
+}
+
+// Next returns a fresh ID.
+func (s *Sequence) Next() int {
+	return int(s.last.Add(1))
+}
+
+// Observe records an ID supplied by a caller so Next never hands it out.
+func (s *Sequence) Observe(id int) {
+	for {
+		cur := s.last.Load()
+		if int64(id) <= cur || s.last.CompareAndSwap(cur, int64(id)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package integrity
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Mode decides what happens to a write when the service owning a referenced
+// record cannot be reached.
+type Mode int
+
+const (
+	// Strict rejects the write with 503 when the dependency is down.
+	Strict Mode = iota
+	// Lenient accepts the write unchecked; the orphan report catches it later.
+	Lenient
+)
+
+// ModeFromEnv reads "strict" or "lenient" from the given variable,
+// defaulting to Strict.
+func ModeFromEnv(key string) Mode {
+	if strings.EqualFold(os.Getenv(key), "lenient") {
+		return Lenient
+	}
+	return Strict
+}
+
+func (m Mode) String() string {
+	if m == Lenient {
+		return "lenient"
+	}
+	return "strict"
+}
+
+// Check interprets the error from looking up a referenced record. A missing
+// record becomes a validation error on field; an unreachable dependency is
+// returned as-is in Strict mode and ignored in Lenient mode.
+func (m Mode) Check(err error, field string) error {
+	switch {
+	case err == nil:
+		return nil
+	case apperr.Is(err, apperr.KindNotFound):
+		return apperr.Field(field, "does not exist")
+	case m == Lenient:
+		log.Printf("integrity: skipping %s check: %v", field, err)
+		return nil
+	default:
+		return err
+	}
+}
+
+// Orphan is a record whose reference points at nothing.
+type Orphan struct {
+	ID    int    `json:"id"`
+	Field string `json:"field"`
+	RefID int    `json:"ref_id"`
+}
+
+// Report is the result of one orphan scan.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Checked     int       `json:"checked"`
+	Orphans     []Orphan  `json:"orphans"`
+	// Unchecked counts records whose reference could not be verified
+	// because the dependency was unavailable.
+	Unchecked int `json:"unchecked"`
+}
+
+// ScanFunc produces a fresh report.
+type ScanFunc func(ctx context.Context) Report
+
+// Reporter runs a ScanFunc periodically and serves the latest report.
+type Reporter struct {
+	scan ScanFunc
+
+	mu   sync.RWMutex
+	last *Report
+}
+
+func NewReporter(scan ScanFunc) *Reporter {
+	return &Reporter{scan: scan}
+}
+
+// Run scans immediately and then every interval until ctx is done.
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		r.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (r *Reporter) refresh(ctx context.Context) {
+	scanCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	rep := r.scan(scanCtx)
+	r.mu.Lock()
+	r.last = &rep
+	r.mu.Unlock()
+	if n := len(rep.Orphans); n > 0 {
+		log.Printf("integrity: %d orphaned records out of %d", n, rep.Checked)
+	}
+}
+
+// Latest returns the most recent report, or ok=false before the first scan.
+func (r *Reporter) Latest() (Report, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.last == nil {
+		return Report{}, false
+	}
+	return *r.last, true
+}
+
+// IntervalFromEnv parses a duration from the given variable, falling back
+// to def when unset or invalid.
+func IntervalFromEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
+// ErrNoReport is returned by handlers before the first scan completes.
+var ErrNoReport = apperr.Unavailable(nil, "orphan report not ready yet")
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// RED metrics shared by every service router.
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status class.",
+	}, []string{"route", "method", "class"})
+
+	duration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	inFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+)
+
+// Handler serves the Prometheus exposition format for /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Unmatched is the route of requests no ServeMux pattern matched.
+const Unmatched = "unmatched"
+
+type routeKey struct{}
+
+// Route returns the ServeMux pattern Middleware matched for r, or Unmatched.
+func Route(r *http.Request) string {
+	if route, ok := r.Context().Value(routeKey{}).(string); ok {
+		return route
+	}
+	return Unmatched
+}
+
+// Middleware records RED metrics for every request that goes through mux,
+// including requests answered early by the middleware in mw (auth, rate
+// limiting), which run in order between the metrics layer and mux. The route
+// label is the matched ServeMux pattern so raw paths don't blow up label
+// cardinality; the same pattern names the request's server span and is
+// available to mw through Route.
+func Middleware(mux *http.ServeMux, mw ...func(http.Handler) http.Handler) http.Handler {
+	var next http.Handler = mux
+	for i := len(mw) - 1; i >= 0; i-- {
+		next = mw[i](next)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = Unmatched
+		} else {
+			tracing.SetRoute(r.Context(), r.Method, route)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, route))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(sw, r)
+
+		duration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		requests.WithLabelValues(route, r.Method, statusClass(sw.status)).Inc()
+	})
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape,
+// e.g. the number of records held by a repository.
+func GaugeFunc(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
+
+func statusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,91 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
+// Error writes a problem for a plain HTTP status (bad query params,
+// unsupported methods and the like).
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	write(w, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
+
+// Write maps a controller error onto a problem response. Errors that are
+// not *apperr.Error become an opaque 500 so internals don't leak.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var e *apperr.Error
+	if !errors.As(err, &e) {
+		log.Printf("%s %s: internal error: %v", r.Method, r.URL.Path, err)
+		Error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+	status := StatusFor(e.Kind)
+	p := Problem{
+		Type:     "urn:opentable:problem:" + e.Kind.String(),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   e.Msg,
+		Instance: r.URL.Path,
+		Errors:   e.Fields,
+	}
+	if e.Kind == apperr.KindInternal {
+		log.Printf("%s %s: internal error: %v", r.Method, r.URL.Path, err)
+		p.Detail = "internal error"
+	}
+	write(w, p)
+}
+
+// StatusFor returns the HTTP status used for a domain error kind.
+func StatusFor(k apperr.Kind) int {
+	switch k {
+	case apperr.KindNotFound:
+		return http.StatusNotFound
+	case apperr.KindValidation:
+		return http.StatusBadRequest
+	case apperr.KindConflict:
+		return http.StatusConflict
+	case apperr.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case apperr.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case apperr.KindForbidden:
+		return http.StatusForbidden
+	case apperr.KindRateLimited:
+		return http.StatusTooManyRequests
+	case apperr.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case apperr.KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func write(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Rate tokens per second refill a bucket holding at
+// most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result describes the state of a bucket after a Take.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token is available (zero when
+	// Allowed).
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Backend stores buckets. The in-memory backend limits per instance; a
+// shared implementation (e.g. on Redis) makes limits hold across every
+// instance registered in Consul.
+type Backend interface {
+	Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Memory is a Backend local to one process. Idle buckets are dropped once
+// they have refilled.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket)}
+}
+
+func (m *Memory) Take(_ context.Context, key string, l Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepIdle(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / l.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(l.Burst) - b.tokens) / l.Rate)
+	return res, nil
+}
+
+// sweepIdle drops full buckets at most once a minute so the map stays small.
+func (m *Memory) sweepIdle(now time.Time) {
+	if now.Sub(m.sweep) < time.Minute {
+		return
+	}
+	m.sweep = now
+	for k, b := range m.buckets {
+		if now.Sub(b.last) > 10*time.Minute {
+			delete(m.buckets, k)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+)
+
+// With no time passing, concurrent takes on one key admit exactly Burst.
+func TestMemoryConcurrentTakeNeverExceedsBurst(t *testing.T) {
+	const workers, perWorker = 50, 20
+	l := Limit{Rate: 1, Burst: 40}
+	m := NewMemory()
+	now := time.Now()
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				res, err := m.Take(context.Background(), "client|GET /x", l, now)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if res.Allowed {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := allowed.Load(); n != int32(l.Burst) {
+		t.Fatalf("admitted %d, want exactly burst %d", n, l.Burst)
+	}
+}
+
+// Through the middleware, one client hammering one route gets at most Burst
+// requests through (the refill rate is negligible over the test).
+func TestMiddlewareConcurrentClientLimitedToBurst(t *testing.T) {
+	const workers = 200
+	cfg := Config{Default: Limit{Rate: 1e-6, Burst: 10}}
+	var served atomic.Int32
+	h := Middleware(cfg, NewMemory())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+	}))
+
+	var rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code == http.StatusTooManyRequests {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := served.Load(); n != int32(cfg.Default.Burst) {
+		t.Fatalf("served %d, want burst %d", n, cfg.Default.Burst)
+	}
+	if n := rejected.Load(); n != workers-int32(cfg.Default.Burst) {
+		t.Fatalf("rejected %d, want %d", n, workers-cfg.Default.Burst)
+	}
+}
+
+// Other services get the Internal quota under their own key, so they neither
+// share the per-route bucket of their IP nor exhaust it for anonymous clients.
+func TestMiddlewareServicesUseInternalLimit(t *testing.T) {
+	cfg := Config{Default: Limit{Rate: 1e-6, Burst: 2}, Internal: Limit{Rate: 1e-6, Burst: 5}}
+	h := Middleware(cfg, NewMemory())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(p *auth.Principal) int {
+		req := httptest.NewRequest(http.MethodGet, "/reviews/summary", nil)
+		req.RemoteAddr = "10.0.0.2:1234"
+		if p != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), *p))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	svc := &auth.Principal{Subject: "service:metadata", Roles: []string{auth.RoleService}}
+	for i := 0; i < 5; i++ {
+		if code := do(svc); code != http.StatusOK {
+			t.Fatalf("service call %d: status %d", i, code)
+		}
+	}
+	if code := do(svc); code != http.StatusTooManyRequests {
+		t.Fatalf("service call past internal burst: status %d", code)
+	}
+	// The anonymous bucket for the same IP is untouched
+	for i := 0; i < 2; i++ {
+		if code := do(nil); code != http.StatusOK {
+			t.Fatalf("anonymous call %d: status %d", i, code)
+		}
+	}
+}
+
+// PreAuth throttles requests carrying the header per IP before the remote
+// check, and leaves requests without it alone.
+func TestPreAuthLimitsHeaderRequestsPerIP(t *testing.T) {
+	cfg := Config{PreAuth: Limit{Rate: 1e-6, Burst: 3}}
+	checks := 0
+	h := PreAuth(cfg, "X-API-Key", NewMemory())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "" {
+			checks++
+		}
+	}))
+	do := func(ip, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/reviews", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for i := 0; i < 5; i++ {
+		want := http.StatusOK
+		if i >= 3 {
+			want = http.StatusTooManyRequests
+		}
+		if code := do("10.0.0.3", "otk_junk_"+strconv.Itoa(i)); code != want {
+			t.Fatalf("key request %d: status %d, want %d", i, code, want)
+		}
+	}
+	if checks != 3 {
+		t.Errorf("%d keys reached the check, want 3", checks)
+	}
+	if code := do("10.0.0.3", ""); code != http.StatusOK {
+		t.Errorf("request without a key: status %d", code)
+	}
+	if code := do("10.0.0.4", "otk_a_b"); code != http.StatusOK {
+		t.Errorf("key from another IP: status %d", code)
+	}
+}
+
+// Buckets and the rejected metric follow the matched pattern: IDs in the
+// path and junk paths can't open fresh buckets or series.
+func TestMiddlewareKeysByRoutePattern(t *testing.T) {
+	cfg := Config{Default: Limit{Rate: 1e-6, Burst: 3}, Routes: map[string]Limit{"POST /reviews": {Rate: 1e-6, Burst: 1}}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reviews", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/reviews/photos/", func(w http.ResponseWriter, r *http.Request) {})
+	h := metrics.Middleware(mux, Middleware(cfg, NewMemory()))
+	do := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.5:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	before := series(t)
+
+	for _, tc := range []struct {
+		method string
+		paths  []string
+		status int // until the bucket runs dry
+	}{
+		{http.MethodGet, []string{"/reviews/photos/1/a.jpg", "/reviews/photos/2/b.jpg", "/reviews/photos/3/c.jpg", "/reviews/photos/4/d.jpg"}, http.StatusOK},
+		{http.MethodGet, []string{"/junk/1", "/junk/2", "/other", "/x/y/z"}, http.StatusNotFound},
+		{"BREW", []string{"/reviews", "/reviews", "/reviews", "/reviews"}, http.StatusOK},
+	} {
+		for i, path := range tc.paths {
+			want := tc.status
+			if i == 3 {
+				want = http.StatusTooManyRequests
+			}
+			if code := do(tc.method, path); code != want {
+				t.Fatalf("%s %s: status %d, want %d", tc.method, path, code, want)
+			}
+		}
+	}
+	// Per-route limits still match on the pattern
+	if code := do(http.MethodPost, "/reviews"); code != http.StatusOK {
+		t.Fatalf("first POST: status %d", code)
+	}
+	if code := do(http.MethodPost, "/reviews"); code != http.StatusTooManyRequests {
+		t.Fatalf("second POST: status %d", code)
+	}
+	if n := series(t) - before; n > 4 {
+		t.Errorf("%d new rejected series, want at most 4", n)
+	}
+}
+
+// series counts the label sets of the rejected counter.
+func series(t *testing.T) int {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 64)
+	rejected.Collect(ch)
+	close(ch)
+	return len(ch)
+}
@@ -0,0 +1,207 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
+)
+
+var rejected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ratelimit_rejected_total",
+	Help: "Requests rejected by the rate limiter by route.",
+}, []string{"route"})
+
+// Config selects the limit for each request.
+type Config struct {
+	// Default applies to routes without an entry in Routes.
+	Default Limit
+	// Routes maps "METHOD pattern" (the ServeMux pattern, e.g.
+	// "POST /reviews") to its own limit.
+	Routes map[string]Limit
+	// Internal replaces the route limits for other services (auth.RoleService
+	// tokens), each keyed by its own name. Zero exempts them.
+	Internal Limit
+	// PreAuth limits, per IP, requests carrying a credential that is checked
+	// remotely before auth runs (see PreAuth). Zero disables it.
+	PreAuth Limit
+}
+
+// ConfigFromEnv starts from def and applies RATE_LIMIT_DEFAULT ("rate:burst",
+// rate per second), RATE_LIMIT_ROUTES ("POST /reviews=0.2:5;..."),
+// RATE_LIMIT_INTERNAL and RATE_LIMIT_PREAUTH ("rate:burst").
+func ConfigFromEnv(def Config) (Config, error) {
+	cfg := Config{Default: def.Default, Routes: make(map[string]Limit, len(def.Routes)), Internal: def.Internal, PreAuth: def.PreAuth}
+	for k, v := range def.Routes {
+		cfg.Routes[k] = v
+	}
+	if v := os.Getenv("RATE_LIMIT_DEFAULT"); v != "" {
+		l, err := ParseLimit(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("RATE_LIMIT_DEFAULT: %w", err)
+		}
+		cfg.Default = l
+	}
+	if v := os.Getenv("RATE_LIMIT_INTERNAL"); v != "" {
+		l, err := ParseLimit(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("RATE_LIMIT_INTERNAL: %w", err)
+		}
+		cfg.Internal = l
+	}
+	if v := os.Getenv("RATE_LIMIT_PREAUTH"); v != "" {
+		l, err := ParseLimit(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("RATE_LIMIT_PREAUTH: %w", err)
+		}
+		cfg.PreAuth = l
+	}
+	if v := os.Getenv("RATE_LIMIT_ROUTES"); v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			route, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok {
+				return Config{}, fmt.Errorf("RATE_LIMIT_ROUTES: %q is not route=rate:burst", entry)
+			}
+			l, err := ParseLimit(spec)
+			if err != nil {
+				return Config{}, fmt.Errorf("RATE_LIMIT_ROUTES %q: %w", route, err)
+			}
+			cfg.Routes[strings.TrimSpace(route)] = l
+		}
+	}
+	return cfg, nil
+}
+
+// ParseLimit parses "rate:burst", e.g. "0.5:10".
+func ParseLimit(s string) (Limit, error) {
+	r, b, ok := strings.Cut(s, ":")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not rate:burst", s)
+	}
+	rate, err := strconv.ParseFloat(strings.TrimSpace(r), 64)
+	if err != nil || rate <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate %q", r)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(b))
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid burst %q", b)
+	}
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// Middleware limits each client per route. Clients are keyed by principal
+// (user, API key or service) when authenticated and by remote IP otherwise,
+// so it must run after the auth middleware. Routes are the patterns
+// metrics.Middleware matched, so it must also run inside that. Backend
+// errors fail open.
+func Middleware(cfg Config, b Backend) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeOf(r)
+			l, ok := cfg.Routes[route]
+			if !ok {
+				l = cfg.Default
+			}
+			if p, ok := auth.FromContext(r.Context()); ok && p.IsService() {
+				l = cfg.Internal
+			}
+			if l.Rate <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allow(w, r, b, clientKey(r)+"|"+route, route, l) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// PreAuth limits requests that carry header by remote IP alone, ahead of a
+// middleware that checks that credential remotely (the API key check calls
+// the apikey service for every key it sees). Requests without the header
+// pass untouched; Middleware still limits them, and the verified callers,
+// after auth.
+func PreAuth(cfg Config, header string, b Backend) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.PreAuth.Rate <= 0 || r.Header.Get(header) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if allow(w, r, b, ipKey(r)+"|"+header, "preauth", cfg.PreAuth) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow takes a token from key's bucket and sets the RateLimit headers. It
+// writes the 429 itself and returns false when the bucket is empty. Backend
+// errors fail open.
+func allow(w http.ResponseWriter, r *http.Request, b Backend, key, label string, l Limit) bool {
+	res, err := b.Take(r.Context(), key, l, time.Now())
+	if err != nil {
+		log.Printf("ratelimit: backend error, allowing request: %v", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(l.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		rejected.WithLabelValues(label).Inc()
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		problem.Write(w, r, apperr.RateLimited("too many requests"))
+		return false
+	}
+	return true
+}
+
+// routeOf is "METHOD pattern" for the route r matched, or "unmatched" for
+// every path nothing matched. Other methods share one key, so clients can't
+// spread requests over fresh buckets or metric series.
+func routeOf(r *http.Request) string {
+	route := metrics.Route(r)
+	if route == metrics.Unmatched {
+		return route
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return r.Method + " " + route
+	}
+	return "OTHER " + route
+}
+
+func clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "sub:" + p.Subject
+	}
+	return ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Weekdays are the lowercase day names schedules are keyed by, indexed by
+// time.Weekday.
+var Weekdays = [...]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// Weekday parses a lowercase day name.
+func Weekday(name string) (time.Weekday, bool) {
+	for d, n := range Weekdays {
+		if n == name {
+			return time.Weekday(d), true
+		}
+	}
+	return 0, false
+}
+
+// Clock parses "HH:MM" into minutes after midnight; "24:00" only as the end
+// of a range.
+func Clock(s string, end bool) (int, bool) {
+	if len(s) != 5 || s[2] != ':' {
+		return 0, false
+	}
+	for _, i := range []int{0, 1, 3, 4} {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	h, m := int(s[0]-'0')*10+int(s[1]-'0'), int(s[3]-'0')*10+int(s[4]-'0')
+	switch {
+	case m > 59:
+		return 0, false
+	case h == 24 && m == 0 && end:
+		return 24 * 60, true
+	case h > 23:
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// Range is a wall-clock range starting on some local date, in minutes after
+// that date's midnight. End is past 24h for ranges that run past midnight.
+type Range struct {
+	Start, End int
+}
+
+// ParseRange parses "HH:MM" bounds. An end not after start runs past
+// midnight; "24:00" ends at midnight.
+func ParseRange(start, end string) (Range, bool) {
+	s, ok1 := Clock(start, false)
+	e, ok2 := Clock(end, true)
+	if !ok1 || !ok2 {
+		return Range{}, false
+	}
+	if e <= s {
+		e += 24 * 60
+	}
+	return Range{Start: s, End: e}, true
+}
+
+// Active reports whether instant t falls in one of the ranges that start on
+// its local date in loc, or on the day before (late ranges still running).
+// Ranges are resolved per date, so a DST change shortens or lengthens the
+// affected range instead of shifting it.
+func Active(t time.Time, loc *time.Location, ranges func(day time.Time) []Range) bool {
+	y, mo, d := t.In(loc).Date()
+	for _, off := range []int{-1, 0} {
+		day := time.Date(y, mo, d+off, 0, 0, 0, 0, loc)
+		for _, r := range ranges(day) {
+			if !t.Before(wall(day, r.Start)) && t.Before(wall(day, r.End)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wall is the instant minutes after midnight of day, in day's location. A
+// wall time skipped by a DST change maps to the instant the clocks jump;
+// time.Date alone would resolve it an hour early.
+func wall(day time.Time, minutes int) time.Time {
+	t := time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, day.Location())
+	want := time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, time.UTC)
+	y, mo, d := t.Date()
+	got := time.Date(y, mo, d, t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if diff := want.Sub(got); diff > 0 {
+		return t.Add(diff)
+	}
+	return t
+}
+
+// Loaded timezones; time.LoadLocation reads the zoneinfo database each call
+var locations sync.Map
+
+// Location loads an IANA timezone, caching it. "Local" is rejected, since it
+// would depend on the server's configuration.
+func Location(name string) (*time.Location, error) {
+	if l, ok := locations.Load(name); ok {
+		return l.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	l, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, l)
+	return l, nil
+}
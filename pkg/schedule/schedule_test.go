@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActive(t *testing.T) {
+	ny, err := Location("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every day 18:00-02:00, Sundays also 11:00-15:00
+	ranges := func(day time.Time) []Range {
+		late, _ := ParseRange("18:00", "02:00")
+		out := []Range{late}
+		if day.Weekday() == time.Sunday {
+			brunch, _ := ParseRange("11:00", "15:00")
+			out = append(out, brunch)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		at   string
+		want bool
+	}{
+		{"2025-06-01T12:00:00-04:00", true},  // Sunday brunch
+		{"2025-06-02T12:00:00-04:00", false}, // Monday noon
+		{"2025-06-02T01:30:00-04:00", true},  // Sunday's late range, after midnight
+		{"2025-06-02T02:00:00-04:00", false}, // end is exclusive
+		{"2025-06-01T17:59:59-04:00", false},
+		// Spring forward: 02:00 doesn't exist on 2025-03-09, the range ends at 03:00 EDT
+		{"2025-03-09T06:30:00Z", true}, // 01:30 EST
+		{"2025-03-09T07:30:00Z", false},
+	} {
+		at, _ := time.Parse(time.RFC3339, tc.at)
+		if got := Active(at, ny, ranges); got != tc.want {
+			t.Errorf("Active(%s) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		end  bool
+		want int
+		ok   bool
+	}{
+		{"00:00", false, 0, true},
+		{"23:59", false, 23*60 + 59, true},
+		{"24:00", true, 24 * 60, true},
+		{"24:00", false, 0, false},
+		{"12:60", false, 0, false},
+		{"+1:30", false, 0, false},
+		{"9:30", false, 0, false},
+	} {
+		got, ok := Clock(tc.in, tc.end)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("Clock(%q, %v) = %d, %v; want %d, %v", tc.in, tc.end, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Init installs a global tracer provider and W3C traceparent propagation.
+// Spans are exported via OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT; with no
+// endpoint configured spans are still created (and propagated) but dropped.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(Resource(serviceName)),
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		expOpts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+		if strings.HasPrefix(endpoint, "http://") {
+			expOpts = append(expOpts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, expOpts...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	Install(tp)
+	return tp.Shutdown, nil
+}
+
+// Install makes tp the global tracer provider and sets W3C traceparent and
+// baggage propagation.
+func Install(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// Resource describes serviceName for the spans it exports.
+func Resource(serviceName string) *resource.Resource {
+	return resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))
+}
+
+// Handler wraps a router so every request gets a server span and continues
+// any incoming traceparent. The span is named after the method until the
+// router matches a pattern (see SetRoute); raw paths carry IDs and photo
+// keys, so they never become span names.
+func Handler(h http.Handler, service string) http.Handler {
+	return otelhttp.NewHandler(h, service, otelhttp.WithSpanNameFormatter(
+		func(_ string, r *http.Request) string {
+			return r.Method
+		},
+	))
+}
+
+// SetRoute names the server span in ctx "METHOD pattern" and records the
+// pattern as http.route, once the router knows which one matched.
+func SetRoute(ctx context.Context, method, pattern string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetName(method + " " + pattern)
+	span.SetAttributes(semconv.HTTPRoute(pattern))
+}
+
+// Transport wraps a client transport so outgoing calls get a client span
+// and carry the traceparent header.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt)
+}
+
+// Start starts an internal span using the global tracer for the given scope.
+func Start(ctx context.Context, scope, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span (if any) and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,135 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing/tracingtest"
+)
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	names := make([]string, 0, len(spans))
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	t.Fatalf("no span %q; got %v", name, names)
+	return tracetest.SpanStub{}
+}
+
+func attr(s tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// A client call through Transport reaches a server wrapped in Handler: the
+// traceparent links both sides into one trace, and internal spans started
+// in the handler nest under the server span.
+func TestHandlerAndTransportExportLinkedSpans(t *testing.T) {
+	exp, shutdown := tracingtest.NewInMemory("test")
+	defer shutdown(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "metadata/controller", "repository.GetByID", attribute.Int("metadata.id", 7))
+		tracing.End(span, nil)
+		_, span = tracing.Start(r.Context(), "metadata/controller", "geocoder.Geocode")
+		tracing.End(span, errors.New("lookup failed"))
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(tracing.Handler(metrics.Middleware(mux), "metadata"))
+	defer srv.Close()
+
+	client := &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+	ctx, parent := tracing.Start(context.Background(), "restaurant/gateway", "metadata.GetByID")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/metadata?id=7", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	spans := exp.GetSpans()
+	gateway := spanNamed(t, spans, "metadata.GetByID")
+	server := spanNamed(t, spans, "GET /metadata")
+	repo := spanNamed(t, spans, "repository.GetByID")
+	geocode := spanNamed(t, spans, "geocoder.Geocode")
+
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span kind = %v", server.SpanKind)
+	}
+	if server.SpanContext.TraceID() != gateway.SpanContext.TraceID() {
+		t.Errorf("server span is in trace %s, want the caller's %s", server.SpanContext.TraceID(), gateway.SpanContext.TraceID())
+	}
+	if !server.Parent.IsRemote() {
+		t.Error("server span parent should come from the incoming traceparent")
+	}
+	if v, ok := attr(server, "http.status_code"); !ok || v.AsInt64() != http.StatusOK {
+		t.Errorf("server span status code attribute = %v, %v", v.Emit(), ok)
+	}
+	if repo.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("repository span should be a child of the server span")
+	}
+	if v, ok := attr(repo, "metadata.id"); !ok || v.AsInt64() != 7 {
+		t.Errorf("metadata.id attribute = %v, %v", v.Emit(), ok)
+	}
+	if repo.Status.Code != codes.Unset {
+		t.Errorf("repository span status = %v, want unset", repo.Status.Code)
+	}
+	if geocode.Status.Code != codes.Error || geocode.Status.Description != "lookup failed" || len(geocode.Events) == 0 {
+		t.Errorf("failed span status = %v %q, events %d", geocode.Status.Code, geocode.Status.Description, len(geocode.Events))
+	}
+	if v, ok := attr(server, "http.route"); !ok || v.AsString() != "/metadata" {
+		t.Errorf("http.route attribute = %v, %v", v.Emit(), ok)
+	}
+	if v, ok := attr(server, "service.name"); ok {
+		t.Errorf("service.name belongs on the resource, found attribute %v", v.Emit())
+	}
+	if got := server.Resource.Attributes(); len(got) == 0 {
+		t.Error("server span has no resource attributes")
+	}
+}
+
+// Span names use the matched pattern, never the raw path, so IDs and photo
+// keys stay out of them; unmatched paths are named after the method alone.
+func TestHandlerNamesSpansByRoute(t *testing.T) {
+	exp, shutdown := tracingtest.NewInMemory("test")
+	defer shutdown(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reviews/photos/", func(w http.ResponseWriter, r *http.Request) {})
+	h := tracing.Handler(metrics.Middleware(mux), "review")
+
+	for _, path := range []string{"/reviews/photos/12/abc.jpg", "/no/such/path"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var names []string
+	for _, s := range exp.GetSpans() {
+		names = append(names, s.Name)
+	}
+	want := []string{"GET /reviews/photos/", "GET"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("span names = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,24 @@
+// Package tracingtest records spans in memory for tests. It is kept apart
+// from pkg/tracing so service binaries don't link the SDK's test exporter.
+package tracingtest
+
+import (
+	"context"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// NewInMemory installs a tracer provider that records finished spans in
+// memory and returns the exporter so tests can inspect them.
+func NewInMemory(serviceName string) (*tracetest.InMemoryExporter, func(context.Context) error) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(tracing.Resource(serviceName)),
+		sdktrace.WithSyncer(exp),
+	)
+	tracing.Install(tp)
+	return exp, tp.Shutdown
+}
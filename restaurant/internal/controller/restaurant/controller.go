@@ -2,24 +2,48 @@ package restaurant
 
 import (
 	"context"
-	"fmt"
 	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	"github.com/ChristopherLeo15/opentable/pkg/idgen"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
 	m "github.com/ChristopherLeo15/opentable/restaurant/internal/model"
 	metagw "github.com/ChristopherLeo15/opentable/restaurant/internal/gateway/metadata/http"
 	metamodel "github.com/ChristopherLeo15/opentable/metadata/model"
 )
 
+const tracerScope = "restaurant/controller"
+
 // Manages restaurants in memory and fetches details from metadata service.
 type Controller struct {
 	mu    sync.RWMutex
 	items []m.Restaurant
+	byID  map[int]int // position in items
+	seq   idgen.Sequence
+
+	// Menus have their own lock, never held together with mu
+	menuMu  sync.RWMutex
+	menus   map[int]m.Menu
+	menuSeq idgen.Sequence
+
+	// Reservations likewise have their own lock
+	resMu        sync.RWMutex
+	reservations map[int]m.Reservation
+	resSeq       idgen.Sequence
 
 	metagw *metagw.Gateway
+	// What to do with writes when the metadata service is down
+	mode integrity.Mode
 }
 
-func New(gw *metagw.Gateway) *Controller {
-	return &Controller{metagw: gw, items: make([]m.Restaurant, 0, 16)}
+func New(gw *metagw.Gateway, mode integrity.Mode) *Controller {
+	return &Controller{metagw: gw, mode: mode, items: make([]m.Restaurant, 0, 16), byID: make(map[int]int, 16), menus: make(map[int]m.Menu), reservations: make(map[int]m.Reservation)}
 }
 
 func (c *Controller) List(ctx context.Context) []m.Restaurant {
@@ -30,47 +54,185 @@ func (c *Controller) List(ctx context.Context) []m.Restaurant {
 	return out
 }
 
+func (c *Controller) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
 func (c *Controller) GetByID(ctx context.Context, id int) (m.Restaurant, *metamodel.Metadata, error) {
 	if id <= 0 {
-		return m.Restaurant{}, nil, fmt.Errorf("id must be positive")
+		return m.Restaurant{}, nil, apperr.Field("id", "must be positive")
 	}
 
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	for _, r := range c.items {
-		if r.ID == id {
-			md, err := c.metagw.GetByID(ctx, r.MetadataID)
-			if err != nil {
-				// return restaurant even if metadata lookup fails
-				return r, nil, nil
-			}
-			return r, &md, nil
-		}
+	i, ok := c.byID[id]
+	var r m.Restaurant
+	if ok {
+		r = c.items[i]
 	}
-	return m.Restaurant{}, nil, fmt.Errorf("restaurant not found")
+	c.mu.RUnlock()
+	if !ok {
+		return m.Restaurant{}, nil, apperr.NotFound("restaurant %d not found", id)
+	}
+
+	ctx, span := tracing.Start(ctx, tracerScope, "metadata.GetByID", attribute.Int("metadata.id", r.MetadataID))
+	md, err := c.metagw.GetByID(ctx, r.MetadataID)
+	tracing.End(span, err)
+	if err != nil {
+		// return restaurant even if metadata lookup fails
+		return r, nil, nil
+	}
+	return r, &md, nil
 }
 
 func (c *Controller) Add(ctx context.Context, x m.Restaurant) (m.Restaurant, error) {
-	if x.DisplayName == "" {
-		return m.Restaurant{}, fmt.Errorf("display_name is required")
+	p, err := authz.Authorize(ctx, authz.RestaurantCreate)
+	if err != nil {
+		return m.Restaurant{}, err
 	}
-	if x.MetadataID <= 0 {
-		return m.Restaurant{}, fmt.Errorf("metadata_id must be positive")
+	// Owners always own what they create; admins may assign an owner
+	if !authz.IsAdmin(p) || x.OwnerID == "" {
+		if x.OwnerID != "" && x.OwnerID != p.Subject {
+			return m.Restaurant{}, apperr.Forbidden("cannot create a restaurant for another owner")
+		}
+		x.OwnerID = p.Subject
+	}
+
+	if err := c.validate(ctx, x); err != nil {
+		return m.Restaurant{}, err
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if x.ID == 0 {
-		next := 1
-		for _, cur := range c.items {
-			if cur.ID >= next {
-				next = cur.ID + 1
-			}
+		x.ID = c.seq.Next()
+	} else {
+		if _, ok := c.byID[x.ID]; ok {
+			return m.Restaurant{}, apperr.Conflict("restaurant %d already exists", x.ID)
 		}
-		x.ID = next
+		c.seq.Observe(x.ID)
 	}
 
+	x.Version = 1
+	c.byID[x.ID] = len(c.items)
 	c.items = append(c.items, x)
 	return x, nil
+}
+
+// Update replaces a restaurant. Only its owner (or an admin) may do so, and
+// only admins can hand it to another owner. pre (from If-Match) must allow
+// the stored version.
+func (c *Controller) Update(ctx context.Context, id int, pre etag.Precondition, x m.Restaurant) (m.Restaurant, error) {
+	if id <= 0 {
+		return m.Restaurant{}, apperr.Field("id", "must be positive")
+	}
+	if x.ID != 0 && x.ID != id {
+		return m.Restaurant{}, apperr.Field("id", "does not match the record being updated")
+	}
+	x.ID = id
+
+	c.mu.RLock()
+	i, ok := c.byID[id]
+	var cur m.Restaurant
+	if ok {
+		cur = c.items[i]
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return m.Restaurant{}, apperr.NotFound("restaurant %d not found", id)
+	}
+
+	// Fail fast before the metadata lookup; repeated below on the record
+	// the write applies to, as ownership may change in between
+	owner := x.OwnerID
+	var err error
+	if x.OwnerID, err = authorizeUpdate(ctx, cur, owner); err != nil {
+		return m.Restaurant{}, err
+	}
+
+	if err := c.validate(ctx, x); err != nil {
+		return m.Restaurant{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, ok = c.byID[id]
+	if !ok {
+		return m.Restaurant{}, apperr.NotFound("restaurant %d not found", id)
+	}
+	// Checked under the write lock so the version and owner can't move in between
+	cur = c.items[i]
+	if x.OwnerID, err = authorizeUpdate(ctx, cur, owner); err != nil {
+		return m.Restaurant{}, err
+	}
+	if !pre.Allows(cur.Version) {
+		return m.Restaurant{}, apperr.PreconditionFailed("restaurant %d has changed (now version %d)", id, cur.Version)
+	}
+	x.Version = cur.Version + 1
+	c.items[i] = x
+	return x, nil
+}
+
+// authorizeUpdate checks that the caller may change cur and returns the
+// owner the update keeps: owner when an admin transfers the record,
+// cur.OwnerID otherwise.
+func authorizeUpdate(ctx context.Context, cur m.Restaurant, owner string) (string, error) {
+	p, err := authz.AuthorizeOwner(ctx, authz.RestaurantManage, cur.OwnerID)
+	if err != nil {
+		return "", err
+	}
+	if owner == "" || !authz.IsAdmin(p) {
+		if owner != "" && owner != cur.OwnerID {
+			return "", apperr.Forbidden("only admins can transfer ownership")
+		}
+		return cur.OwnerID, nil
+	}
+	return owner, nil
+}
+
+// validate checks fields and that the referenced metadata exists.
+func (c *Controller) validate(ctx context.Context, x m.Restaurant) error {
+	var fields []apperr.FieldError
+	if x.DisplayName == "" {
+		fields = append(fields, apperr.FieldError{Field: "display_name", Message: "is required"})
+	}
+	if x.MetadataID <= 0 {
+		fields = append(fields, apperr.FieldError{Field: "metadata_id", Message: "must be positive"})
+	}
+	if x.ID < 0 {
+		fields = append(fields, apperr.FieldError{Field: "id", Message: "must be positive"})
+	}
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+
+	// Referenced metadata must exist
+	ctx, span := tracing.Start(ctx, tracerScope, "metadata.GetByID", attribute.Int("metadata.id", x.MetadataID))
+	_, err := c.metagw.GetByID(ctx, x.MetadataID)
+	tracing.End(span, err)
+	return c.mode.Check(err, "metadata_id")
+}
+
+// ScanOrphans lists restaurants whose metadata_id no longer resolves.
+func (c *Controller) ScanOrphans(ctx context.Context) integrity.Report {
+	items := c.List(ctx)
+	rep := integrity.Report{GeneratedAt: time.Now().UTC(), Checked: len(items), Orphans: []integrity.Orphan{}}
+
+	all, err := c.metagw.List(ctx)
+	if err != nil {
+		rep.Unchecked = len(items)
+		return rep
+	}
+	known := make(map[int]bool, len(all))
+	for _, md := range all {
+		known[md.ID] = true
+	}
+	for _, r := range items {
+		if !known[r.MetadataID] {
+			rep.Orphans = append(rep.Orphans, integrity.Orphan{ID: r.ID, Field: "metadata_id", RefID: r.MetadataID})
+		}
+	}
+	return rep
 }
\ No newline at end of file
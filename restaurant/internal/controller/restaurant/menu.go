@@ -0,0 +1,199 @@
+package restaurant
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	m "github.com/ChristopherLeo15/opentable/restaurant/internal/model"
+)
+
+// Menus lists a restaurant's menus in creation order.
+func (c *Controller) Menus(ctx context.Context, restaurantID int) ([]m.Menu, error) {
+	if restaurantID <= 0 {
+		return nil, apperr.Field("restaurant_id", "must be positive")
+	}
+	if _, ok := c.restaurant(restaurantID); !ok {
+		return nil, apperr.NotFound("restaurant %d not found", restaurantID)
+	}
+	c.menuMu.RLock()
+	out := make([]m.Menu, 0)
+	for _, x := range c.menus {
+		if x.RestaurantID == restaurantID {
+			out = append(out, x)
+		}
+	}
+	c.menuMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (c *Controller) Menu(ctx context.Context, id int) (m.Menu, error) {
+	if id <= 0 {
+		return m.Menu{}, apperr.Field("id", "must be positive")
+	}
+	c.menuMu.RLock()
+	x, ok := c.menus[id]
+	c.menuMu.RUnlock()
+	if !ok {
+		return m.Menu{}, apperr.NotFound("menu %d not found", id)
+	}
+	return x, nil
+}
+
+// AddMenu creates a menu for a restaurant the caller manages.
+func (c *Controller) AddMenu(ctx context.Context, x m.Menu) (m.Menu, error) {
+	if x.ID < 0 {
+		return m.Menu{}, apperr.Field("id", "must be positive")
+	}
+	x.Normalize()
+	if err := x.Validate(); err != nil {
+		return m.Menu{}, err
+	}
+	if err := c.manage(ctx, x.RestaurantID); err != nil {
+		return m.Menu{}, err
+	}
+
+	c.menuMu.Lock()
+	defer c.menuMu.Unlock()
+	if x.ID == 0 {
+		x.ID = c.menuSeq.Next()
+	} else {
+		if _, ok := c.menus[x.ID]; ok {
+			return m.Menu{}, apperr.Conflict("menu %d already exists", x.ID)
+		}
+		c.menuSeq.Observe(x.ID)
+	}
+	x.Version = 1
+	c.menus[x.ID] = x
+	return x, nil
+}
+
+// UpdateMenu replaces a menu. A menu stays with its restaurant; pre (from
+// If-Match) must allow the stored version.
+func (c *Controller) UpdateMenu(ctx context.Context, id int, pre etag.Precondition, x m.Menu) (m.Menu, error) {
+	cur, err := c.Menu(ctx, id)
+	if err != nil {
+		return m.Menu{}, err
+	}
+	if x.ID != 0 && x.ID != id {
+		return m.Menu{}, apperr.Field("id", "does not match the menu being updated")
+	}
+	if x.RestaurantID != 0 && x.RestaurantID != cur.RestaurantID {
+		return m.Menu{}, apperr.Field("restaurant_id", "cannot be changed")
+	}
+	x.ID, x.RestaurantID = id, cur.RestaurantID
+	x.Normalize()
+	if err := x.Validate(); err != nil {
+		return m.Menu{}, err
+	}
+	if err := c.manage(ctx, x.RestaurantID); err != nil {
+		return m.Menu{}, err
+	}
+
+	c.menuMu.Lock()
+	defer c.menuMu.Unlock()
+	cur, ok := c.menus[id]
+	if !ok {
+		return m.Menu{}, apperr.NotFound("menu %d not found", id)
+	}
+	// Checked under the write lock so the version can't move in between
+	if !pre.Allows(cur.Version) {
+		return m.Menu{}, apperr.PreconditionFailed("menu %d has changed (now version %d)", id, cur.Version)
+	}
+	x.Version = cur.Version + 1
+	c.menus[id] = x
+	return x, nil
+}
+
+func (c *Controller) DeleteMenu(ctx context.Context, id int) error {
+	cur, err := c.Menu(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := c.manage(ctx, cur.RestaurantID); err != nil {
+		return err
+	}
+	c.menuMu.Lock()
+	delete(c.menus, id)
+	c.menuMu.Unlock()
+	return nil
+}
+
+// DietaryItem is a menu item carrying the requested tags.
+type DietaryItem struct {
+	MenuID   int    `json:"menu_id"`
+	MenuName string `json:"menu_name"`
+	Section  string `json:"section"`
+	Item     m.Item `json:"item"`
+}
+
+type DietaryMatch struct {
+	Restaurant m.Restaurant  `json:"restaurant"`
+	Items      []DietaryItem `json:"items"`
+}
+
+// Dietary finds restaurants serving at least one item that carries every
+// tag. When at is set, only items available at that instant count.
+func (c *Controller) Dietary(ctx context.Context, tags []string, at time.Time) ([]DietaryMatch, error) {
+	if len(tags) == 0 {
+		return nil, apperr.Field("tags", "is required")
+	}
+	want := make([]string, 0, len(tags))
+	for _, t := range tags {
+		n, ok := m.NormalizeTag(t)
+		if !ok {
+			return nil, apperr.Field("tags", "must be among vegan, vegetarian, gluten_free, dairy_free, nut_free, halal, kosher")
+		}
+		want = append(want, n)
+	}
+
+	byRestaurant := make(map[int][]DietaryItem)
+	c.menuMu.RLock()
+	for _, x := range c.menus {
+		for _, s := range x.Sections {
+			for _, it := range s.Items {
+				if !it.HasTags(want) || !at.IsZero() && !x.ItemAvailableAt(it, at) {
+					continue
+				}
+				byRestaurant[x.RestaurantID] = append(byRestaurant[x.RestaurantID], DietaryItem{MenuID: x.ID, MenuName: x.Name, Section: s.Name, Item: it})
+			}
+		}
+	}
+	c.menuMu.RUnlock()
+
+	out := make([]DietaryMatch, 0, len(byRestaurant))
+	for id, items := range byRestaurant {
+		r, ok := c.restaurant(id)
+		if !ok {
+			continue
+		}
+		sort.SliceStable(items, func(i, j int) bool { return items[i].MenuID < items[j].MenuID })
+		out = append(out, DietaryMatch{Restaurant: r, Items: items})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Restaurant.ID < out[j].Restaurant.ID })
+	return out, nil
+}
+
+func (c *Controller) restaurant(id int) (m.Restaurant, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i, ok := c.byID[id]
+	if !ok {
+		return m.Restaurant{}, false
+	}
+	return c.items[i], true
+}
+
+// manage checks that the restaurant exists and the caller may manage it.
+func (c *Controller) manage(ctx context.Context, restaurantID int) error {
+	r, ok := c.restaurant(restaurantID)
+	if !ok {
+		return apperr.NotFound("restaurant %d not found", restaurantID)
+	}
+	_, err := authz.AuthorizeOwner(ctx, authz.RestaurantManage, r.OwnerID)
+	return err
+}
@@ -0,0 +1,139 @@
+package restaurant
+
+import (
+	"context"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	m "github.com/ChristopherLeo15/opentable/restaurant/internal/model"
+)
+
+// Book reserves a table. Diners book for themselves (admins for anyone);
+// partners book for the diner they name with an API key holding
+// apikey.ScopeReservationsCreate.
+func (c *Controller) Book(ctx context.Context, x m.Reservation) (m.Reservation, error) {
+	if x.ID < 0 {
+		return m.Reservation{}, apperr.Field("id", "must be positive")
+	}
+	x.Normalize()
+	p, err := authorizeBooking(ctx, &x)
+	if err != nil {
+		return m.Reservation{}, err
+	}
+	if err := x.Validate(time.Now()); err != nil {
+		return m.Reservation{}, err
+	}
+	if _, ok := c.restaurant(x.RestaurantID); !ok {
+		return m.Reservation{}, apperr.NotFound("restaurant %d not found", x.RestaurantID)
+	}
+	x.Status, x.BookedBy, x.Version = m.ReservationBooked, p.Subject, 1
+
+	c.resMu.Lock()
+	defer c.resMu.Unlock()
+	if x.ID == 0 {
+		x.ID = c.resSeq.Next()
+	} else {
+		if _, ok := c.reservations[x.ID]; ok {
+			return m.Reservation{}, apperr.Conflict("reservation %d already exists", x.ID)
+		}
+		c.resSeq.Observe(x.ID)
+	}
+	c.reservations[x.ID] = x
+	return x, nil
+}
+
+// authorizeBooking checks that the caller may book for x.DinerID, filling
+// it in for diners who leave it out.
+func authorizeBooking(ctx context.Context, x *m.Reservation) (auth.Principal, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return p, apperr.Unauthenticated("authentication required")
+	}
+	if len(p.Scopes) > 0 {
+		// The key check enforces the scope per route; this keeps keys
+		// without it out should the route map ever drift
+		if !p.HasScope(apikey.ScopeReservationsCreate) {
+			return p, apperr.Forbidden("API key lacks the %s scope", apikey.ScopeReservationsCreate)
+		}
+		return p, nil
+	}
+	if _, err := authz.Authorize(ctx, authz.ReservationCreate); err != nil {
+		return p, err
+	}
+	if x.DinerID == "" {
+		x.DinerID = p.Subject
+	}
+	if x.DinerID != p.Subject && !authz.IsAdmin(p) {
+		return p, apperr.Forbidden("diners can only book for themselves")
+	}
+	return p, nil
+}
+
+// Reservation returns a booking to its diner, whoever booked it, the
+// restaurant's owner, admins and other services (reviews verify visits).
+func (c *Controller) Reservation(ctx context.Context, id int) (m.Reservation, error) {
+	if id <= 0 {
+		return m.Reservation{}, apperr.Field("id", "must be positive")
+	}
+	c.resMu.RLock()
+	x, ok := c.reservations[id]
+	c.resMu.RUnlock()
+	if !ok {
+		return m.Reservation{}, apperr.NotFound("reservation %d not found", id)
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return m.Reservation{}, apperr.Unauthenticated("authentication required")
+	}
+	if p.IsService() || authz.IsAdmin(p) || p.Subject == x.DinerID || p.Subject == x.BookedBy || c.owns(p, x.RestaurantID) {
+		return x, nil
+	}
+	return m.Reservation{}, apperr.Forbidden("not allowed to view reservation %d", id)
+}
+
+// SetReservationStatus moves a booked reservation on. The restaurant's owner
+// (or an admin) records completed visits and no-shows; the diner and
+// whoever booked it may also cancel. pre (from If-Match) must allow the
+// stored version.
+func (c *Controller) SetReservationStatus(ctx context.Context, id int, pre etag.Precondition, status string) (m.Reservation, error) {
+	cur, err := c.Reservation(ctx, id)
+	if err != nil {
+		return m.Reservation{}, err
+	}
+	if !m.ValidReservationStatus(status) {
+		return m.Reservation{}, apperr.Field("status", "must be completed, cancelled or no_show")
+	}
+	p, _ := auth.FromContext(ctx)
+	manager := authz.IsAdmin(p) || c.owns(p, cur.RestaurantID)
+	switch {
+	case status == m.ReservationCancelled && (p.Subject == cur.DinerID || p.Subject == cur.BookedBy):
+	case status != m.ReservationBooked && manager:
+	default:
+		return m.Reservation{}, apperr.Forbidden("not allowed to mark reservation %d %s", id, status)
+	}
+
+	c.resMu.Lock()
+	defer c.resMu.Unlock()
+	x := c.reservations[id]
+	// Checked under the write lock so the version can't move in between
+	if !pre.Allows(x.Version) {
+		return m.Reservation{}, apperr.PreconditionFailed("reservation %d has changed (now version %d)", id, x.Version)
+	}
+	if x.Status != m.ReservationBooked {
+		return m.Reservation{}, apperr.Conflict("reservation %d is already %s", id, x.Status)
+	}
+	x.Status = status
+	x.Version++
+	c.reservations[id] = x
+	return x, nil
+}
+
+// owns reports whether p owns the restaurant with the given id.
+func (c *Controller) owns(p auth.Principal, restaurantID int) bool {
+	r, ok := c.restaurant(restaurantID)
+	return ok && r.OwnerID != "" && r.OwnerID == p.Subject && authz.Can(p.Roles, authz.RestaurantManage)
+}
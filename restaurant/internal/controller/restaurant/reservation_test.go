@@ -0,0 +1,123 @@
+package restaurant
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	m "github.com/ChristopherLeo15/opentable/restaurant/internal/model"
+)
+
+func withRestaurant(owner string) *Controller {
+	c := New(nil, integrity.Strict)
+	c.items = append(c.items, m.Restaurant{ID: 1, OwnerID: owner, Version: 1})
+	c.byID[1] = 0
+	return c
+}
+
+func as(p auth.Principal) context.Context {
+	return auth.WithPrincipal(context.Background(), p)
+}
+
+func TestBookAuthorization(t *testing.T) {
+	diner := auth.Principal{Subject: "diner-1", Roles: []string{"diner"}}
+	tests := []struct {
+		name      string
+		caller    *auth.Principal
+		diner     string
+		want      apperr.Kind // zero when the booking succeeds
+		bookedFor string      // diner the booking ends up for
+	}{
+		{name: "anonymous", want: apperr.KindUnauthenticated},
+		{name: "diner for themselves", caller: &diner, bookedFor: "diner-1"},
+		{name: "diner names themselves", caller: &diner, diner: "diner-1", bookedFor: "diner-1"},
+		{name: "diner for someone else", caller: &diner, diner: "diner-2", want: apperr.KindForbidden},
+		{name: "owner cannot book", caller: &auth.Principal{Subject: "owner-1", Roles: []string{"owner"}}, want: apperr.KindForbidden},
+		{name: "admin for anyone", caller: &auth.Principal{Subject: "root", Roles: []string{"admin"}}, diner: "diner-2", bookedFor: "diner-2"},
+		{name: "partner key with scope", caller: &auth.Principal{Subject: "apikey:k1", Scopes: []string{apikey.ScopeReservationsCreate}}, diner: "diner-3", bookedFor: "diner-3"},
+		{name: "partner key must name the diner", caller: &auth.Principal{Subject: "apikey:k1", Scopes: []string{apikey.ScopeReservationsCreate}}, want: apperr.KindValidation},
+		{name: "partner key without scope", caller: &auth.Principal{Subject: "apikey:k2", Scopes: []string{apikey.ScopeMetadataRead}}, diner: "diner-3", want: apperr.KindForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withRestaurant("owner-1")
+			ctx := context.Background()
+			if tt.caller != nil {
+				ctx = as(*tt.caller)
+			}
+			x, err := c.Book(ctx, m.Reservation{RestaurantID: 1, DinerID: tt.diner, PartySize: 2, Time: time.Now().Add(time.Hour)})
+			if tt.want != 0 {
+				if !apperr.Is(err, tt.want) {
+					t.Fatalf("err = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x.DinerID != tt.bookedFor || x.BookedBy != tt.caller.Subject || x.Status != m.ReservationBooked {
+				t.Errorf("booked %+v", x)
+			}
+		})
+	}
+}
+
+// Only the restaurant side records visits; diners and partners may cancel,
+// and a settled reservation stays settled.
+func TestSetReservationStatus(t *testing.T) {
+	diner := as(auth.Principal{Subject: "diner-1", Roles: []string{"diner"}})
+	owner := as(auth.Principal{Subject: "owner-1", Roles: []string{"owner"}})
+	otherOwner := as(auth.Principal{Subject: "owner-2", Roles: []string{"owner"}})
+	partner := as(auth.Principal{Subject: "apikey:k1", Scopes: []string{apikey.ScopeReservationsCreate}})
+	anyVersion := etag.Precondition{Any: true}
+
+	c := withRestaurant("owner-1")
+	book := func(ctx context.Context, dinerID string) int {
+		x, err := c.Book(ctx, m.Reservation{RestaurantID: 1, DinerID: dinerID, PartySize: 2, Time: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x.ID
+	}
+
+	id := book(diner, "")
+	if _, err := c.SetReservationStatus(diner, id, anyVersion, m.ReservationCompleted); !apperr.Is(err, apperr.KindForbidden) {
+		t.Errorf("diner completed their own visit: %v", err)
+	}
+	if _, err := c.SetReservationStatus(otherOwner, id, anyVersion, m.ReservationCompleted); !apperr.Is(err, apperr.KindForbidden) {
+		t.Errorf("another restaurant's owner completed the visit: %v", err)
+	}
+	if _, err := c.SetReservationStatus(owner, id, etag.Precondition{Versions: []int{5}}, m.ReservationCompleted); !apperr.Is(err, apperr.KindPreconditionFailed) {
+		t.Errorf("stale If-Match: %v", err)
+	}
+	x, err := c.SetReservationStatus(owner, id, etag.Precondition{Versions: []int{1}}, m.ReservationCompleted)
+	if err != nil || x.Status != m.ReservationCompleted || x.Version != 2 {
+		t.Fatalf("owner completes: %+v, %v", x, err)
+	}
+	if _, err := c.SetReservationStatus(diner, id, anyVersion, m.ReservationCancelled); !apperr.Is(err, apperr.KindConflict) {
+		t.Errorf("cancelled a completed visit: %v", err)
+	}
+
+	id = book(partner, "diner-1")
+	if _, err := c.SetReservationStatus(partner, id, anyVersion, m.ReservationCancelled); err != nil {
+		t.Errorf("partner cancels its booking: %v", err)
+	}
+	id = book(partner, "diner-1")
+	if _, err := c.SetReservationStatus(diner, id, anyVersion, m.ReservationBooked); !apperr.Is(err, apperr.KindForbidden) {
+		t.Errorf("moved back to booked: %v", err)
+	}
+	if _, err := c.SetReservationStatus(diner, id, anyVersion, m.ReservationCancelled); err != nil {
+		t.Errorf("diner cancels a partner booking made for them: %v", err)
+	}
+	if _, err := c.Reservation(as(auth.Principal{Subject: "diner-2", Roles: []string{"diner"}}), id); !apperr.Is(err, apperr.KindForbidden) {
+		t.Errorf("another diner read the reservation: %v", err)
+	}
+	if _, err := c.Reservation(as(auth.Principal{Subject: "service:review", Roles: []string{auth.RoleService}}), id); err != nil {
+		t.Errorf("review service read the reservation: %v", err)
+	}
+}
@@ -7,28 +7,22 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"os"
-	"sync"
 	"time"
 
 	meta "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/discovery"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
 )
 
 // Gateway discovers the metadata service via Consul (no env fallback).
 type Gateway struct {
-	consulAddr string
-	client     *http.Client
-
-	mu        sync.RWMutex
-	cachedURL string
-	expires   time.Time
+	client   *http.Client
+	resolver *discovery.Resolver
 }
 
 func New() *Gateway {
-	consul := os.Getenv("CONSUL_HTTP_ADDR")
-	if consul == "" {
-		consul = "http://consul:8500"
-	}
 	tr := &http.Transport{
 		DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
 		TLSHandshakeTimeout:   5 * time.Second,
@@ -36,70 +30,14 @@ func New() *Gateway {
 		IdleConnTimeout:       30 * time.Second,
 		MaxIdleConns:          10,
 	}
-	return &Gateway{
-		consulAddr: consul,
-		client:     &http.Client{Transport: tr},
-	}
-}
-
-// baseURL resolves metadata via Consul and always either returns a URL or panics back to caller via error on use.
-func (g *Gateway) baseURL(ctx context.Context) (string, error) {
-	// hit cache
-	g.mu.RLock()
-	if time.Now().Before(g.expires) && g.cachedURL != "" {
-		u := g.cachedURL
-		g.mu.RUnlock()
-		return u, nil
-	}
-	g.mu.RUnlock()
-
-	// ask Consul: GET /v1/health/service/metadata?passing=true
-	type svc struct {
-		Service struct {
-			Address string
-			Port    int
-		}
-		Node struct {
-			Address string
-		}
-	}
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, g.consulAddr+"/v1/health/service/metadata?passing=true", nil)
-	resp, err := g.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("consul query failed: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("consul query status: %d", resp.StatusCode)
-	}
-
-	var arr []svc
-	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&arr); err != nil {
-		return "", fmt.Errorf("consul decode failed: %w", err)
-	}
-	if len(arr) == 0 {
-		return "", fmt.Errorf("no healthy metadata instances in consul")
-	}
-
-	addr := arr[0].Service.Address
-	if addr == "" {
-		addr = arr[0].Node.Address
-	}
-	if addr == "" || arr[0].Service.Port == 0 {
-		return "", fmt.Errorf("consul result missing address/port")
-	}
-
-	u := fmt.Sprintf("http://%s:%d", addr, arr[0].Service.Port)
-	g.mu.Lock()
-	g.cachedURL = u
-	g.expires = time.Now().Add(30 * time.Second)
-	g.mu.Unlock()
-	return u, nil
+	consul := &http.Client{Transport: tracing.Transport(tr)}
+	client := &http.Client{Transport: auth.ServiceTransport(consul.Transport)}
+	return &Gateway{client: client, resolver: discovery.NewResolver("metadata", consul).Observe(observeLookup)}
 }
 
 // ResolveBaseURL returns the resolved URL (for /debug/metadata) or an error.
 func (g *Gateway) ResolveBaseURL(ctx context.Context) (string, error) {
-	return g.baseURL(ctx)
+	return g.resolver.BaseURL(ctx)
 }
 
 // Health calls metadata /healthz and returns (url, status, error).
@@ -109,7 +47,7 @@ func (g *Gateway) Health(ctx context.Context) (string, int, error) {
 		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 	}
-	base, err := g.baseURL(ctx)
+	base, err := g.resolver.BaseURL(ctx)
 	if err != nil {
 		return "", 0, err
 	}
@@ -127,15 +65,21 @@ func (g *Gateway) Health(ctx context.Context) (string, int, error) {
 	return u, resp.StatusCode, nil
 }
 
-func (g *Gateway) get(ctx context.Context, path string, out any) error {
+// Cap on single-record responses. Listings grow with the data and the
+// orphan scan needs all of them, so List reads them uncapped.
+const maxRecordBytes = 1 << 20
+
+// get decodes the JSON response for path into out, reading at most max
+// bytes when max > 0.
+func (g *Gateway) get(ctx context.Context, path string, max int64, out any) error {
 	if _, has := ctx.Deadline(); !has {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 	}
-	base, err := g.baseURL(ctx)
+	base, err := g.resolver.BaseURL(ctx)
 	if err != nil {
-		return err
+		return apperr.Unavailable(err, "metadata service unavailable")
 	}
 	u := base + path
 
@@ -145,20 +89,38 @@ func (g *Gateway) get(ctx context.Context, path string, out any) error {
 	}
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return err
+		observeDownstreamError(0)
+		return apperr.Unavailable(err, "metadata service unavailable")
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("metadata %s -> %d", path, resp.StatusCode)
+		observeDownstreamError(resp.StatusCode)
+		if resp.StatusCode == http.StatusNotFound {
+			return apperr.NotFound("metadata %s not found", path)
+		}
+		return apperr.Unavailable(fmt.Errorf("metadata %s -> %d", path, resp.StatusCode), "metadata service unavailable")
+	}
+	var body io.Reader = resp.Body
+	if max > 0 {
+		body = io.LimitReader(resp.Body, max)
 	}
-	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+	return json.NewDecoder(body).Decode(out)
 }
 
 func (g *Gateway) GetByID(ctx context.Context, id int) (meta.Metadata, error) {
 	var m meta.Metadata
-	if err := g.get(ctx, fmt.Sprintf("/metadata?id=%d", id), &m); err != nil {
+	if err := g.get(ctx, fmt.Sprintf("/metadata?id=%d", id), maxRecordBytes, &m); err != nil {
 		return meta.Metadata{}, err
 	}
 	return m, nil
+}
+
+// List returns every metadata record; used by the orphan scan.
+func (g *Gateway) List(ctx context.Context) ([]meta.Metadata, error) {
+	var out []meta.Metadata
+	if err := g.get(ctx, "/metadata", 0, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
\ No newline at end of file
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	consulLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "metadata_gateway_consul_lookups_total",
+		Help: "Consul lookups for the metadata service by result.",
+	}, []string{"result"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "metadata_gateway_cache_requests_total",
+		Help: "Resolved-URL cache requests by result (hit or miss).",
+	}, []string{"result"})
+
+	downstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "metadata_gateway_downstream_errors_total",
+		Help: "Failed calls to the metadata service by HTTP status (\"transport\" when no response).",
+	}, []string{"status"})
+)
+
+func init() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "metadata_gateway_cache_hit_ratio",
+		Help: "Share of resolved-URL cache requests served from cache.",
+	}, cacheHitRatio)
+}
+
+func cacheHitRatio() float64 {
+	hits := counterValue(cacheRequests.WithLabelValues("hit"))
+	misses := counterValue(cacheRequests.WithLabelValues("miss"))
+	if hits+misses == 0 {
+		return 0
+	}
+	return hits / (hits + misses)
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil || m.Counter == nil {
+		return 0
+	}
+	return m.Counter.GetValue()
+}
+
+// observeLookup records each metadata URL resolution.
+func observeLookup(hit bool, err error) {
+	if hit {
+		cacheRequests.WithLabelValues("hit").Inc()
+		return
+	}
+	cacheRequests.WithLabelValues("miss").Inc()
+	if err != nil {
+		consulLookups.WithLabelValues("error").Inc()
+	} else {
+		consulLookups.WithLabelValues("ok").Inc()
+	}
+}
+
+func observeDownstreamError(status int) {
+	label := "transport"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	downstreamErrors.WithLabelValues(label).Inc()
+}
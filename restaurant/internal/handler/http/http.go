@@ -4,28 +4,42 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	ctrl "github.com/ChristopherLeo15/opentable/restaurant/internal/controller/restaurant"
 	m "github.com/ChristopherLeo15/opentable/restaurant/internal/model"
 	metamodel "github.com/ChristopherLeo15/opentable/metadata/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/etag"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
 )
 
 type Handler struct {
-	c *ctrl.Controller
+	c       *ctrl.Controller
+	orphans *integrity.Reporter
 }
 
-func New(c *ctrl.Controller) *Handler {
-	return &Handler{c: c}
+func New(c *ctrl.Controller, orphans *integrity.Reporter) *Handler {
+	return &Handler{c: c, orphans: orphans}
 }
 
-func (h *Handler) Router() http.Handler {
+// Router wires the routes; mw (auth, rate limiting, ...) wraps every route.
+func (h *Handler) Router(mw ...func(http.Handler) http.Handler) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/restaurants", h.handleRestaurants)
+	mux.HandleFunc("/restaurants/dietary", h.getDietary)
+	mux.HandleFunc("/menus", h.handleMenus)
+	mux.HandleFunc("/reservations", h.handleReservations)
+	mux.HandleFunc("/orphans", h.getOrphans)
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	return mux
+	mux.Handle("/metrics", metrics.Handler())
+	return metrics.Middleware(mux, mw...)
 }
 
 func (h *Handler) handleRestaurants(w http.ResponseWriter, r *http.Request) {
@@ -34,8 +48,10 @@ func (h *Handler) handleRestaurants(w http.ResponseWriter, r *http.Request) {
 		h.getRestaurants(w, r)
 	case http.MethodPost:
 		h.postRestaurant(w, r)
+	case http.MethodPut:
+		h.putRestaurant(w, r)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -48,10 +64,33 @@ func (h *Handler) getRestaurants(w http.ResponseWriter, r *http.Request) {
 	}
 	id, err := strconv.Atoi(q)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	rest, meta, err := h.c.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// The body embeds metadata, so the ETag covers both versions and the
+	// computed open_now; metadata that couldn't be fetched counts as version 0
+	inputs := []int{0}
+	if meta != nil {
+		inputs[0] = meta.Version
+		if meta.OpenNow != nil {
+			inputs = append(inputs, 0)
+			if *meta.OpenNow {
+				inputs[1] = 1
+			}
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+	}
+	tag := etag.Derive(rest.Version, inputs...)
+	w.Header().Set("ETag", tag)
+	if etag.NoneMatch(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
-	rest, meta, _ := h.c.GetByID(r.Context(), id)
 	type response struct {
 		Restaurant m.Restaurant        `json:"restaurant"`
 		Metadata   *metamodel.Metadata `json:"metadata,omitempty"`
@@ -63,17 +102,277 @@ func (h *Handler) postRestaurant(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	var in m.Restaurant
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "invalid json body", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
 		return
 	}
 	out, err := h.c.Add(r.Context(), in)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", etag.Format(out.Version))
 	writeJSON(w, http.StatusCreated, out)
 }
 
+func (h *Handler) putRestaurant(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	pre, err := etag.IfMatch(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	var in m.Restaurant
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	out, err := h.c.Update(r.Context(), id, pre, in)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) handleMenus(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getMenus(w, r)
+	case http.MethodPost:
+		h.postMenu(w, r)
+	case http.MethodPut:
+		h.putMenu(w, r)
+	case http.MethodDelete:
+		h.deleteMenu(w, r)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// getMenus: GET /menus?id= for one menu, or ?restaurant_id= for a
+// restaurant's menus
+func (h *Handler) getMenus(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if v := q.Get("id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			problem.Error(w, r, http.StatusBadRequest, "invalid id")
+			return
+		}
+		out, err := h.c.Menu(r.Context(), id)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		w.Header().Set("ETag", etag.Format(out.Version))
+		if etag.NotModified(r, out.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+	restaurantID, err := strconv.Atoi(q.Get("restaurant_id"))
+	if err != nil || restaurantID <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid restaurant_id")
+		return
+	}
+	out, err := h.c.Menus(r.Context(), restaurantID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) postMenu(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var in m.Menu
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	out, err := h.c.AddMenu(r.Context(), in)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (h *Handler) putMenu(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	pre, err := etag.IfMatch(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	var in m.Menu
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	out, err := h.c.UpdateMenu(r.Context(), id, pre, in)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) deleteMenu(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.c.DeleteMenu(r.Context(), id); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleReservations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getReservation(w, r)
+	case http.MethodPost:
+		h.postReservation(w, r)
+	case http.MethodPut:
+		h.putReservation(w, r)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) getReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	out, err := h.c.Reservation(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	if etag.NotModified(r, out.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) postReservation(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var in m.Reservation
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	out, err := h.c.Book(r.Context(), in)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	writeJSON(w, http.StatusCreated, out)
+}
+
+// putReservation: PUT /reservations?id= with {"status": "completed"}
+func (h *Handler) putReservation(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	pre, err := etag.IfMatch(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	var in struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	out, err := h.c.SetReservationStatus(r.Context(), id, pre, strings.TrimSpace(in.Status))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(out.Version))
+	writeJSON(w, http.StatusOK, out)
+}
+
+// getDietary: GET /restaurants/dietary?tags=vegan,gluten_free&available_at=
+func (h *Handler) getDietary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	var tags []string
+	for _, v := range q["tags"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	var at time.Time
+	switch v := strings.TrimSpace(q.Get("available_at")); v {
+	case "":
+	case "now":
+		at = time.Now()
+	default:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			problem.Write(w, r, apperr.Field("available_at", "must be an RFC 3339 time such as 2025-06-01T19:30:00-04:00, or now"))
+			return
+		}
+		at = t
+	}
+	out, err := h.c.Dietary(r.Context(), tags, at)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Latest dangling-reference report from the periodic scan
+func (h *Handler) getOrphans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rep, ok := h.orphans.Latest()
+	if !ok {
+		problem.Write(w, r, integrity.ErrNoReport)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
 // ----- Support function -----
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
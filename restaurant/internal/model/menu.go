@@ -0,0 +1,348 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/schedule"
+)
+
+// Menu is one of a restaurant's menus (lunch, drinks, ...).
+type Menu struct {
+	ID           int    `json:"id"`
+	RestaurantID int    `json:"restaurant_id"`
+	Name         string `json:"name"`
+	// ISO 4217 code; the default for item prices
+	Currency string `json:"currency"`
+	// IANA name; required when the menu or its items have availability windows
+	Timezone string `json:"timezone,omitempty"`
+	// When the menu is served; empty means always
+	Availability []Window  `json:"availability,omitempty"`
+	Sections     []Section `json:"sections"`
+	// Bumped on every update; exposed as the ETag
+	Version int `json:"version"`
+}
+
+type Section struct {
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+type Item struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Price       Price    `json:"price"`
+	DietaryTags []string `json:"dietary_tags,omitempty"`
+	// Narrows the menu's availability, e.g. a brunch-only dish
+	Availability []Window `json:"availability,omitempty"`
+}
+
+// Price is a decimal amount ("12.50") in a currency, with exactly as many
+// fraction digits as the currency has minor units.
+type Price struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// Window is a weekly time range in the menu's timezone. An end not after
+// start runs past midnight; "24:00" ends at midnight. No days means every
+// day.
+type Window struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// Dietary tags.
+const (
+	TagVegan      = "vegan"
+	TagVegetarian = "vegetarian"
+	TagGlutenFree = "gluten_free"
+	TagDairyFree  = "dairy_free"
+	TagNutFree    = "nut_free"
+	TagHalal      = "halal"
+	TagKosher     = "kosher"
+)
+
+var dietaryTags = map[string]bool{
+	TagVegan: true, TagVegetarian: true, TagGlutenFree: true, TagDairyFree: true,
+	TagNutFree: true, TagHalal: true, TagKosher: true,
+}
+
+// NormalizeTag maps spellings like "Gluten-Free" onto the canonical tag.
+// ok is false for unknown tags.
+func NormalizeTag(s string) (string, bool) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	t = strings.NewReplacer("-", "_", " ", "_").Replace(t)
+	return t, dietaryTags[t]
+}
+
+// Minor units per currency accepted for prices.
+var currencies = map[string]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "INR": 2, "JPY": 0, "KRW": 0, "KWD": 3,
+	"MXN": 2, "NOK": 2, "NZD": 2, "SEK": 2, "SGD": 2, "USD": 2,
+}
+
+const (
+	maxSections     = 50
+	maxItems        = 200
+	maxWindows      = 14
+	maxAmountDigits = 7
+)
+
+// Normalize trims names, canonicalizes tags, days and currencies, and fills
+// item currencies from the menu. Values it can't canonicalize are left
+// for Validate to report.
+func (x *Menu) Normalize() {
+	x.Name = strings.TrimSpace(x.Name)
+	x.Currency = strings.ToUpper(strings.TrimSpace(x.Currency))
+	x.Timezone = strings.TrimSpace(x.Timezone)
+	normalizeWindows(x.Availability)
+	for i := range x.Sections {
+		s := &x.Sections[i]
+		s.Name = strings.TrimSpace(s.Name)
+		for j := range s.Items {
+			it := &s.Items[j]
+			it.Name = strings.TrimSpace(it.Name)
+			it.Description = strings.TrimSpace(it.Description)
+			it.Price.Currency = strings.ToUpper(strings.TrimSpace(it.Price.Currency))
+			if it.Price.Currency == "" {
+				it.Price.Currency = x.Currency
+			}
+			it.Price.Amount = normalizeAmount(strings.TrimSpace(it.Price.Amount), it.Price.Currency)
+			tags := make([]string, 0, len(it.DietaryTags))
+			seen := make(map[string]bool, len(it.DietaryTags))
+			for _, t := range it.DietaryTags {
+				if n, ok := NormalizeTag(t); ok {
+					t = n
+				}
+				if !seen[t] {
+					seen[t] = true
+					tags = append(tags, t)
+				}
+			}
+			sort.Strings(tags)
+			it.DietaryTags = tags
+			normalizeWindows(it.Availability)
+		}
+	}
+}
+
+func normalizeWindows(ws []Window) {
+	for i := range ws {
+		for j, d := range ws[i].Days {
+			ws[i].Days[j] = strings.ToLower(strings.TrimSpace(d))
+		}
+		ws[i].Start = strings.TrimSpace(ws[i].Start)
+		ws[i].End = strings.TrimSpace(ws[i].End)
+	}
+}
+
+// normalizeAmount pads the fraction to the currency's minor units
+// ("12.5" USD -> "12.50"); anything it can't parse is returned unchanged.
+func normalizeAmount(s, currency string) string {
+	minor, ok := currencies[currency]
+	if !ok {
+		return s
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !digits(whole) || hasFrac && !digits(frac) || len(frac) > minor {
+		return s
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if whole == "" {
+		whole = "0"
+	}
+	if minor == 0 {
+		return whole
+	}
+	return whole + "." + frac + strings.Repeat("0", minor-len(frac))
+}
+
+func digits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks every field and reports all violations at once.
+func (x Menu) Validate() error {
+	var fields []apperr.FieldError
+	add := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: field, Message: msg})
+	}
+
+	if x.RestaurantID <= 0 {
+		add("restaurant_id", "must be positive")
+	}
+	switch n := utf8.RuneCountInString(x.Name); {
+	case n == 0:
+		add("name", "is required")
+	case n > 100:
+		add("name", "must be at most 100 characters")
+	}
+	if _, ok := currencies[x.Currency]; !ok {
+		add("currency", "must be a supported ISO 4217 code such as USD")
+	}
+
+	windows := len(x.Availability) > 0
+	addWindows := func(field string, ws []Window) {
+		if len(ws) > maxWindows {
+			add(field, fmt.Sprintf("must have at most %d windows", maxWindows))
+		}
+		for i, w := range ws {
+			f := fmt.Sprintf("%s[%d]", field, i)
+			for _, d := range w.Days {
+				if _, ok := schedule.Weekday(d); !ok {
+					add(f+".days", "must be weekday names such as monday")
+					break
+				}
+			}
+			start, okStart := schedule.Clock(w.Start, false)
+			end, okEnd := schedule.Clock(w.End, true)
+			if !okStart {
+				add(f+".start", "must be a time from 00:00 to 23:59")
+			}
+			if !okEnd {
+				add(f+".end", "must be a time from 00:00 to 24:00")
+			}
+			if okStart && okEnd && start == end {
+				add(f+".end", "must differ from start")
+			}
+		}
+	}
+	addWindows("availability", x.Availability)
+
+	if len(x.Sections) == 0 {
+		add("sections", "is required")
+	} else if len(x.Sections) > maxSections {
+		add("sections", fmt.Sprintf("must have at most %d sections", maxSections))
+	}
+	for i, s := range x.Sections {
+		sf := fmt.Sprintf("sections[%d]", i)
+		switch n := utf8.RuneCountInString(s.Name); {
+		case n == 0:
+			add(sf+".name", "is required")
+		case n > 100:
+			add(sf+".name", "must be at most 100 characters")
+		}
+		if len(s.Items) > maxItems {
+			add(sf+".items", fmt.Sprintf("must have at most %d items", maxItems))
+		}
+		for j, it := range s.Items {
+			f := fmt.Sprintf("%s.items[%d]", sf, j)
+			switch n := utf8.RuneCountInString(it.Name); {
+			case n == 0:
+				add(f+".name", "is required")
+			case n > 100:
+				add(f+".name", "must be at most 100 characters")
+			}
+			if utf8.RuneCountInString(it.Description) > 500 {
+				add(f+".description", "must be at most 500 characters")
+			}
+			if minor, ok := currencies[it.Price.Currency]; !ok {
+				add(f+".price.currency", "must be a supported ISO 4217 code such as USD")
+			} else if !validAmount(it.Price.Amount, minor) {
+				add(f+".price.amount", fmt.Sprintf("must be a non-negative amount with %d decimal places", minor))
+			}
+			for _, t := range it.DietaryTags {
+				if !dietaryTags[t] {
+					add(f+".dietary_tags", "must be among vegan, vegetarian, gluten_free, dairy_free, nut_free, halal, kosher")
+					break
+				}
+			}
+			windows = windows || len(it.Availability) > 0
+			addWindows(f+".availability", it.Availability)
+		}
+	}
+
+	switch {
+	case x.Timezone == "" && windows:
+		add("timezone", "is required when availability windows are set")
+	case x.Timezone != "":
+		if _, err := schedule.Location(x.Timezone); err != nil {
+			add("timezone", "must be an IANA timezone such as America/New_York")
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
+
+// validAmount accepts normalized amounts: no leading zeros and exactly
+// minor fraction digits.
+func validAmount(s string, minor int) bool {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !digits(whole) || len(whole) > maxAmountDigits || len(whole) > 1 && whole[0] == '0' {
+		return false
+	}
+	if minor == 0 {
+		return !hasFrac
+	}
+	return hasFrac && len(frac) == minor && digits(frac)
+}
+
+// HasTags reports whether the item carries every tag in tags.
+func (it Item) HasTags(tags []string) bool {
+	for _, t := range tags {
+		if !slices.Contains(it.DietaryTags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// ItemAvailableAt reports whether it can be ordered at instant t: both the
+// menu's and the item's windows must allow it. Windows are wall-clock times
+// in the menu's timezone (see schedule.Active).
+func (x Menu) ItemAvailableAt(it Item, t time.Time) bool {
+	return x.within(x.Availability, t) && x.within(it.Availability, t)
+}
+
+func (x Menu) within(ws []Window, t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	loc, err := schedule.Location(x.Timezone)
+	if err != nil {
+		return false
+	}
+	return schedule.Active(t, loc, func(day time.Time) []schedule.Range {
+		var out []schedule.Range
+		for _, w := range ws {
+			if !w.on(day.Weekday()) {
+				continue
+			}
+			if r, ok := schedule.ParseRange(w.Start, w.End); ok {
+				out = append(out, r)
+			}
+		}
+		return out
+	})
+}
+
+func (w Window) on(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if wd, ok := schedule.Weekday(name); ok && wd == d {
+			return true
+		}
+	}
+	return false
+}
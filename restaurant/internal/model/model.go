@@ -4,4 +4,10 @@ type Restaurant struct {
 	ID          int    `json:"id"`
 	MetadataID  int    `json:"metadata_id"`
 	DisplayName string `json:"display_name"`
+	// Subject of the owner account allowed to manage this restaurant
+	OwnerID string `json:"owner_id"`
+	// Only accept reviews backed by a completed reservation
+	VerifiedReviewsOnly bool `json:"verified_reviews_only"`
+	// Bumped on every update; exposed as the ETag
+	Version int `json:"version"`
 }
\ No newline at end of file
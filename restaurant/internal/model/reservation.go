@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Reservation statuses. A booking starts out booked and ends in one of the
+// others.
+const (
+	ReservationBooked    = "booked"
+	ReservationCompleted = "completed"
+	ReservationCancelled = "cancelled"
+	ReservationNoShow    = "no_show"
+)
+
+// MaxPartySize is the largest party one reservation can seat.
+const MaxPartySize = 20
+
+// Reservation is a table booked at a restaurant, by the diner or by a
+// partner on their behalf.
+type Reservation struct {
+	ID           int `json:"id"`
+	RestaurantID int `json:"restaurant_id"`
+	// Subject of the diner the table is for
+	DinerID   string    `json:"diner_id"`
+	PartySize int       `json:"party_size"`
+	Time      time.Time `json:"time"`
+	// Set by the controller, never by the client
+	Status string `json:"status"`
+	// Subject that made the booking: the diner, an admin, or "apikey:<id>"
+	BookedBy string `json:"booked_by"`
+	// Bumped on every update; exposed as the ETag
+	Version int `json:"version"`
+}
+
+func (x *Reservation) Normalize() {
+	x.DinerID = strings.TrimSpace(x.DinerID)
+}
+
+// Validate checks a new booking and reports all violations at once.
+func (x Reservation) Validate(now time.Time) error {
+	var fields []apperr.FieldError
+	add := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: field, Message: msg})
+	}
+	if x.RestaurantID <= 0 {
+		add("restaurant_id", "must be positive")
+	}
+	if x.DinerID == "" {
+		add("diner_id", "is required")
+	}
+	if x.PartySize < 1 || x.PartySize > MaxPartySize {
+		add("party_size", "must be between 1 and 20")
+	}
+	switch {
+	case x.Time.IsZero():
+		add("time", "is required")
+	case !x.Time.After(now):
+		add("time", "must be in the future")
+	}
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
+
+// ValidReservationStatus reports whether s is a known status.
+func ValidReservationStatus(s string) bool {
+	switch s {
+	case ReservationBooked, ReservationCompleted, ReservationCancelled, ReservationNoShow:
+		return true
+	}
+	return false
+}
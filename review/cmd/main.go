@@ -14,9 +14,22 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ChristopherLeo15/opentable/review/internal/blob"
+	"github.com/ChristopherLeo15/opentable/review/internal/contentfilter"
 	ctrl "github.com/ChristopherLeo15/opentable/review/internal/controller/review"
+	resgw "github.com/ChristopherLeo15/opentable/review/internal/gateway/reservation/http"
+	reslocal "github.com/ChristopherLeo15/opentable/review/internal/gateway/reservation/local"
+	restgw "github.com/ChristopherLeo15/opentable/review/internal/gateway/restaurant/http"
 	h "github.com/ChristopherLeo15/opentable/review/internal/handler/http"
+	"github.com/ChristopherLeo15/opentable/review/internal/photo"
 	repo "github.com/ChristopherLeo15/opentable/review/internal/repository/memory"
+	"github.com/ChristopherLeo15/opentable/pkg/apikey"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/idempotency"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/ratelimit"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
 )
 
 func main() {
@@ -29,21 +42,130 @@ func main() {
 			port = p
 		}
 	}
+	serviceName := getenvDefault("SERVICE_NAME", "review")
+
+	// Tracing: export via OTLP when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName)
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+
+	// Calls to the other services carry a short-lived service token
+	if err := auth.InitService(serviceName); err != nil {
+		log.Fatalf("service token init: %v", err)
+	}
+
+	// Rate limits; other services get their own, larger quota per caller.
+	// RATE_LIMIT_DEFAULT / RATE_LIMIT_ROUTES / RATE_LIMIT_INTERNAL / RATE_LIMIT_PREAUTH
+	// override these defaults.
+	rl, err := ratelimit.ConfigFromEnv(ratelimit.Config{
+		Default:  ratelimit.Limit{Rate: 20, Burst: 40},
+		Internal: ratelimit.Limit{Rate: 500, Burst: 1000},
+		PreAuth:  ratelimit.Limit{Rate: 20, Burst: 40},
+		Routes: map[string]ratelimit.Limit{
+			"POST /reviews":        {Rate: 0.2, Burst: 5},
+			"POST /reviews/photos": {Rate: 0.2, Burst: 10},
+		},
+	})
+	if err != nil {
+		log.Fatalf("rate limit config: %v", err)
+	}
+	limits := ratelimit.NewMemory()
+
+	// Partners read with an X-API-Key, checked (and metered) by the apikey service;
+	// the same routes stay open to anonymous readers. Requests with a key are limited
+	// per IP first, so junk keys can't flood the apikey service.
+	mw := []func(http.Handler) http.Handler{
+		ratelimit.PreAuth(rl, apikey.Header, limits),
+		apikey.NewClient().Middleware(map[string]string{
+			"GET /reviews":           apikey.ScopeReviewsRead,
+			"GET /reviews/summary":   apikey.ScopeReviewsRead,
+			"GET /reviews/summaries": apikey.ScopeReviewsRead,
+			"GET /reviews/search":    apikey.ScopeReviewsRead,
+		}),
+	}
+
+	// Auth: writes need a valid JWT unless AUTH_DISABLED=true (local runs only)
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("auth disabled: every request runs as admin")
+		mw = append(mw, auth.Static(auth.Principal{Subject: "dev", Roles: []string{"admin"}}))
+	} else {
+		verifier, err := auth.VerifierFromEnv()
+		if err != nil {
+			log.Fatalf("auth init: %v", err)
+		}
+		mw = append(mw, verifier.Middleware)
+	}
+
+	// Rate limiting runs after auth so clients are keyed by principal, not only IP
+	mw = append(mw, ratelimit.Middleware(rl, limits))
+
+	// Retried POSTs with the same Idempotency-Key replay the first response
+	mw = append(mw, idempotency.Middleware(idempotency.NewMemory(), idempotency.TTLFromEnv()))
+
+	// Background jobs stop on shutdown
+	bg, stopBg := context.WithCancel(context.Background())
+	defer stopBg()
+
+	// REVIEW_AUTO_PUBLISH: always (default), never, or min-rating:N
+	publish, err := ctrl.PolicyFromEnv("REVIEW_AUTO_PUBLISH")
+	if err != nil {
+		log.Fatalf("review policy: %v", err)
+	}
+
+	// REVIEW_FILTER_THRESHOLD, REVIEW_COMMENT_MAX_LEN, REVIEW_WORDLIST_FILE
+	content, err := contentfilter.FromEnv()
+	if err != nil {
+		log.Fatalf("content filter: %v", err)
+	}
+
+	// Photos: PHOTO_DIR holds the blobs, PHOTO_URL_SECRET_FILE signs links
+	blobs, err := blob.NewLocal(getenvDefault("PHOTO_DIR", "data/photos"))
+	if err != nil {
+		log.Fatalf("photo store: %v", err)
+	}
+	secret, persistent, err := photo.SecretFromEnv("PHOTO_URL_SECRET_FILE")
+	if err != nil {
+		log.Fatalf("photo url secret: %v", err)
+	}
+	if !persistent {
+		log.Println("PHOTO_URL_SECRET_FILE not set: photo links stop working on restart")
+	}
+	photos := ctrl.PhotoConfig{
+		Store:        blobs,
+		Signer:       photo.NewSigner(secret, "/reviews/photos/", time.Hour),
+		MaxBytes:     int64(getenvInt("PHOTO_MAX_BYTES", 10<<20)),
+		MaxPerReview: 10,
+	}
+
+	// Reservations come from the restaurant service unless RESERVATION_DATA_FILE
+	// points at a local table (runs without the restaurant service)
+	var reservations ctrl.ReservationGateway = resgw.New()
+	if path := os.Getenv("RESERVATION_DATA_FILE"); path != "" {
+		local, err := reslocal.Open(path)
+		if err != nil {
+			log.Fatalf("reservation data: %v", err)
+		}
+		log.Printf("reservations served from %s", path)
+		reservations = local
+	}
 
 	r := repo.New()
-	c := ctrl.New(r)
-	hdlr := h.New(c)
+	c := ctrl.New(r, restgw.New(), reservations, integrity.ModeFromEnv("REFERENCE_CHECK_MODE"), publish, content, photos)
+	metrics.GaugeFunc("review_records", "Number of reviews stored.", func() float64 { return float64(r.Count()) })
+	orphans := integrity.NewReporter(c.ScanOrphans)
+	go orphans.Run(bg, integrity.IntervalFromEnv("ORPHAN_SCAN_INTERVAL", 5*time.Minute))
+	hdlr := h.New(c, orphans)
 
 	srv := &http.Server{
 		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           hdlr.Router(),
+		Handler:           tracing.Handler(hdlr.Router(mw...), serviceName),
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      10 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
 
 	// Register in Consul
-	serviceName := getenvDefault("SERVICE_NAME", "review")
 	consulAddr := getenvDefault("CONSUL_HTTP_ADDR", "http://consul:8500")
 	serviceID := fmt.Sprintf("%s-%d", serviceName, port)
 	if err := registerWithConsul(consulAddr, serviceID, serviceName, "review", port, "/healthz"); err != nil {
@@ -61,6 +183,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
+	stopBg()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -71,6 +194,9 @@ func main() {
 	} else {
 		log.Println("server stopped cleanly")
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("tracing shutdown error: %v", err)
+	}
 }
 
 func getenvDefault(k, def string) string {
@@ -80,6 +206,13 @@ func getenvDefault(k, def string) string {
 	return def
 }
 
+func getenvInt(k string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(k)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
 func registerWithConsul(consul, id, name, dnsName string, port int, healthPath string) error {
 	payload := map[string]any{
 		"ID":      id,
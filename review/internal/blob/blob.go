@@ -0,0 +1,115 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+var ErrNotFound = apperr.NotFound("blob not found")
+
+// BlobStore keeps opaque blobs by key. Keys are generated by the service
+// (letters, digits, '-', '_', '.', and '/' as a separator; no segment
+// starts with '.').
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var keyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+(?:/[A-Za-z0-9_.-]+)*$`)
+
+// ValidKey reports whether key is safe to map onto a path. Segments may not
+// start with '.', which rules out "." and ".." as well as hidden files such
+// as in-progress uploads.
+func ValidKey(key string) bool {
+	if len(key) > 200 || !keyRe.MatchString(key) {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// Local stores blobs as files under a directory.
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	p := filepath.Join(l.dir, filepath.FromSlash(key))
+	// Belt and braces: the key must not resolve outside the directory
+	if rel, err := filepath.Rel(l.dir, p); err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	return p, nil
+}
+
+// Put writes to a temp file and renames it, so readers never see a partial
+// blob.
+func (l *Local) Put(_ context.Context, key string, r io.Reader) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (l *Local) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
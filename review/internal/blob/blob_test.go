@@ -0,0 +1,34 @@
+package blob
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidKeyRejectsDotSegments(t *testing.T) {
+	for _, key := range []string{"12/abc.jpg", "12/abc_thumb.png", "a/b/c"} {
+		if !ValidKey(key) {
+			t.Errorf("ValidKey(%q) = false, want true", key)
+		}
+	}
+	for _, key := range []string{"..", "12/..", "12/../../etc", "12/./abc.jpg", "12/.upload-123", "/abs", "12//abc", ""} {
+		if ValidKey(key) {
+			t.Errorf("ValidKey(%q) = true, want false", key)
+		}
+	}
+}
+
+func TestLocalStaysInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(context.Background(), "12/..", strings.NewReader("x")); err == nil {
+		t.Fatal("Put with a .. segment succeeded")
+	}
+	if _, err := l.Get(context.Background(), "12/../../secret"); err != ErrNotFound {
+		t.Fatalf("Get outside the directory: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,182 @@
+package contentfilter
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultWordlist is used when no wordlist file is configured.
+var DefaultWordlist = []string{
+	"viagra", "casino", "bitcoin", "crypto", "free money", "click here",
+	"buy followers", "promo code", "scam", "idiot", "moron",
+}
+
+// Wordlist flags comments containing listed words or phrases, after undoing
+// common character substitutions (l33t, punctuation between letters).
+type Wordlist struct {
+	phrases [][]string
+}
+
+func NewWordlist(words []string) *Wordlist {
+	w := &Wordlist{}
+	for _, p := range words {
+		if toks := tokens(p); len(toks) > 0 {
+			w.phrases = append(w.phrases, toks)
+		}
+	}
+	return w
+}
+
+func (w *Wordlist) Inspect(comment string) ([]Finding, error) {
+	toks := tokens(comment)
+	var out []Finding
+	for _, p := range w.phrases {
+		if containsPhrase(toks, p) {
+			out = append(out, Finding{Score: 0.5, Reason: "wordlist:" + strings.Join(p, " ")})
+		}
+	}
+	return out, nil
+}
+
+var linkRe = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|co|ru|xyz|info|biz|link|shop)\b`)
+
+// Links flags URLs and bare domains.
+type Links struct{}
+
+func (Links) Inspect(comment string) ([]Finding, error) {
+	if linkRe.MatchString(comment) {
+		return []Finding{{Score: 0.6, Reason: "link"}}, nil
+	}
+	return nil, nil
+}
+
+// phoneRe matches runs of digits with common separators; Phones then counts
+// the digits.
+var phoneRe = regexp.MustCompile(`\+?\(?\d[\d\s().-]{7,}\d`)
+
+// Phones flags anything that looks like a phone number (9+ digits).
+type Phones struct{}
+
+func (Phones) Inspect(comment string) ([]Finding, error) {
+	for _, m := range phoneRe.FindAllString(comment, -1) {
+		digits := 0
+		for _, r := range m {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits >= 9 {
+			return []Finding{{Score: 0.6, Reason: "phone"}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Duplicates flags comments that repeat one of the last n accepted
+// comments, comparing normalized text by word shingles.
+type Duplicates struct {
+	mu     sync.Mutex
+	recent []map[string]bool
+	next   int
+}
+
+func NewDuplicates(n int) *Duplicates {
+	return &Duplicates{recent: make([]map[string]bool, 0, n)}
+}
+
+func (d *Duplicates) Inspect(comment string) ([]Finding, error) {
+	sh := shingles(tokens(comment))
+	if len(sh) < 3 {
+		// Short comments ("Great food!") repeat legitimately
+		return nil, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, prev := range d.recent {
+		if jaccard(sh, prev) >= 0.7 {
+			return []Finding{{Score: 1, Reason: "duplicate"}}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *Duplicates) Observe(comment string) {
+	sh := shingles(tokens(comment))
+	if len(sh) < 3 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.recent) < cap(d.recent) {
+		d.recent = append(d.recent, sh)
+		return
+	}
+	d.recent[d.next] = sh
+	d.next = (d.next + 1) % len(d.recent)
+}
+
+// ----- Support function -----
+
+var leet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+// tokens lowercases s, undoes l33t substitutions inside words (plain
+// numbers are left alone) and drops anything that is not a letter.
+func tokens(s string) []string {
+	var out []string
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		if strings.IndexFunc(w, unicode.IsLetter) >= 0 {
+			w = leet.Replace(w)
+		}
+		w = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) {
+				return r
+			}
+			return -1
+		}, w)
+		if w != "" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func containsPhrase(toks, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(toks); i++ {
+		match := true
+		for j := range phrase {
+			if toks[i+j] != phrase[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// shingles returns the set of consecutive word pairs.
+func shingles(toks []string) map[string]bool {
+	out := make(map[string]bool, len(toks))
+	for i := 0; i+1 < len(toks); i++ {
+		out[toks[i]+" "+toks[i+1]] = true
+	}
+	return out
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	union := len(a) + len(b) - inter
+	if union == 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
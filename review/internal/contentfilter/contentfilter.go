@@ -0,0 +1,145 @@
+package contentfilter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Finding is what one check has to say about a comment.
+type Finding struct {
+	Score  float64
+	Reason string
+}
+
+// Check inspects a comment. A non-nil error rejects the review outright;
+// findings only add to its suspicion score.
+type Check interface {
+	Inspect(comment string) ([]Finding, error)
+}
+
+// Observer is implemented by checks that learn from accepted reviews.
+type Observer interface {
+	Observe(comment string)
+}
+
+// Result is the outcome of running the pipeline.
+type Result struct {
+	Score   float64
+	Reasons []string
+}
+
+// Pipeline runs every check and sums the scores; reviews at or above
+// Threshold go to moderation instead of being published.
+type Pipeline struct {
+	Checks    []Check
+	Threshold float64
+}
+
+// Run inspects comment with every check.
+func (p *Pipeline) Run(comment string) (Result, error) {
+	var res Result
+	for _, c := range p.Checks {
+		findings, err := c.Inspect(comment)
+		if err != nil {
+			return Result{}, err
+		}
+		for _, f := range findings {
+			res.Score += f.Score
+			res.Reasons = append(res.Reasons, f.Reason)
+		}
+	}
+	return res, nil
+}
+
+// Suspicious reports whether res should be held for a moderator.
+func (p *Pipeline) Suspicious(res Result) bool {
+	return p.Threshold > 0 && res.Score >= p.Threshold
+}
+
+// Observe feeds an accepted comment to the checks that keep history.
+func (p *Pipeline) Observe(comment string) {
+	for _, c := range p.Checks {
+		if o, ok := c.(Observer); ok {
+			o.Observe(comment)
+		}
+	}
+}
+
+// FromEnv builds the default pipeline. REVIEW_FILTER_THRESHOLD sets the
+// score that holds a review (default 1), REVIEW_COMMENT_MAX_LEN the hard
+// length limit (default 2000) and REVIEW_WORDLIST_FILE replaces the
+// built-in wordlist (one word per line, # comments).
+func FromEnv() (*Pipeline, error) {
+	threshold := 1.0
+	if v := os.Getenv("REVIEW_FILTER_THRESHOLD"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t < 0 {
+			return nil, fmt.Errorf("REVIEW_FILTER_THRESHOLD: invalid value %q", v)
+		}
+		threshold = t
+	}
+	maxLen := 2000
+	if v := os.Getenv("REVIEW_COMMENT_MAX_LEN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("REVIEW_COMMENT_MAX_LEN: invalid value %q", v)
+		}
+		maxLen = n
+	}
+	words := DefaultWordlist
+	if path := os.Getenv("REVIEW_WORDLIST_FILE"); path != "" {
+		w, err := LoadWordlist(path)
+		if err != nil {
+			return nil, fmt.Errorf("REVIEW_WORDLIST_FILE: %w", err)
+		}
+		words = w
+	}
+
+	return &Pipeline{
+		Checks: []Check{
+			Length{Max: maxLen},
+			NewWordlist(words),
+			Links{},
+			Phones{},
+			NewDuplicates(500),
+		},
+		Threshold: threshold,
+	}, nil
+}
+
+// LoadWordlist reads one word or phrase per line; blank lines and lines
+// starting with # are skipped.
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, sc.Err()
+}
+
+// Length rejects comments over Max characters.
+type Length struct {
+	Max int
+}
+
+func (l Length) Inspect(comment string) ([]Finding, error) {
+	if n := len([]rune(comment)); n > l.Max {
+		return nil, apperr.Field("comment", fmt.Sprintf("must be at most %d characters", l.Max))
+	}
+	return nil, nil
+}
@@ -0,0 +1,191 @@
+package contentfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"at limit", strings.Repeat("a", 10), false},
+		{"over limit", strings.Repeat("a", 11), true},
+		{"counts characters not bytes", strings.Repeat("é", 10), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Length{Max: 10}.Inspect(tc.comment)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if err != nil && !apperr.Is(err, apperr.KindValidation) {
+				t.Fatalf("err = %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestWordlist(t *testing.T) {
+	w := NewWordlist([]string{"casino", "free money", "Scam"})
+	tests := []struct {
+		comment string
+		want    []string
+	}{
+		{"Lovely dinner by the sea", nil},
+		{"Play at our CASINO tonight", []string{"wordlist:casino"}},
+		{"c4s1n0 nights", []string{"wordlist:casino"}},
+		{"c-a-s-i-n-o nights", []string{"wordlist:casino"}},
+		{"Get fr33 m0n3y now", []string{"wordlist:free money"}},
+		{"free, money!", []string{"wordlist:free money"}},
+		{"free dessert, money well spent", nil},
+		{"what a $c@m", []string{"wordlist:scam"}},
+		{"the scampi was great", nil},
+		// Plain numbers are not read as letters
+		{"table for 5 at 7", nil},
+		{"casino scam", []string{"wordlist:casino", "wordlist:scam"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.comment, func(t *testing.T) {
+			got := reasons(t, w, tc.comment)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("reasons = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLinksAndPhones(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    []string
+	}{
+		{"Great steak, friendly staff", nil},
+		{"see https://example.com/deal", []string{"link"}},
+		{"visit www.example.org", []string{"link"}},
+		{"order at cheapmeals.shop", []string{"link"}},
+		{"e.g. the soup", nil},
+		{"call +1 (555) 123-4567", []string{"phone"}},
+		{"text 555.123.4567", []string{"phone"}},
+		{"ext 555-1234", nil},
+		{"we waited 45 minutes for 2 plates", nil},
+		{"visited on 2024-05-14", nil},
+		{"book at deals.xyz or 0044 20 7946 0958", []string{"link", "phone"}},
+	}
+	checks := []Check{Links{}, Phones{}}
+	for _, tc := range tests {
+		t.Run(tc.comment, func(t *testing.T) {
+			var got []string
+			for _, c := range checks {
+				got = append(got, reasons(t, c, tc.comment)...)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("reasons = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	const first = "The pasta was cooked perfectly and the service was friendly"
+	tests := []struct {
+		name     string
+		observed []string
+		comment  string
+		want     bool
+	}{
+		{"nothing seen yet", nil, first, false},
+		{"exact repeat", []string{first}, first, true},
+		{"case and punctuation ignored", []string{first}, "the PASTA was cooked perfectly, and the service was friendly!!", true},
+		{"leet ignored", []string{first}, "The p4sta was c00ked perfectly and the service was friendly", true},
+		{"small edit", []string{first}, "The pasta was cooked perfectly and the service was very friendly", true},
+		{"different review", []string{first}, "Cold fries and a long wait, would not come back", false},
+		{"short comments repeat freely", []string{"Great food!"}, "Great food!", false},
+		{"only the last n are kept", []string{first, "Cold fries and a long wait", "Nice view over the harbour at night"}, first, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDuplicates(2)
+			for _, c := range tc.observed {
+				d.Observe(c)
+			}
+			got := len(reasons(t, d, tc.comment)) > 0
+			if got != tc.want {
+				t.Fatalf("duplicate = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// Hard limits reject the review; findings only hold it for a moderator once
+// their scores reach the threshold.
+func TestPipelineThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		comment   string
+		want      string
+	}{
+		{"clean", 1, "Lovely dinner by the sea", "published"},
+		{"one word stays under", 1, "felt like a casino in there", "published"},
+		{"two words reach it", 1, "casino vibes, total scam", "pending"},
+		{"link and phone", 1, "at eats.shop or +1 555 123 4567", "pending"},
+		{"lower threshold", 0.5, "felt like a casino in there", "pending"},
+		{"zero threshold holds nothing", 0, "casino vibes, total scam", "published"},
+		{"too long", 1, strings.Repeat("a", 41), "rejected"},
+		{"too long and suspicious", 1, "casino scam " + strings.Repeat("a", 40), "rejected"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Pipeline{
+				Checks:    []Check{Length{Max: 40}, NewWordlist(DefaultWordlist), Links{}, Phones{}, NewDuplicates(10)},
+				Threshold: tc.threshold,
+			}
+			got := "published"
+			res, err := p.Run(tc.comment)
+			switch {
+			case err != nil:
+				got = "rejected"
+			case p.Suspicious(res):
+				got = "pending"
+			}
+			if got != tc.want {
+				t.Fatalf("outcome = %s (score %.1f, %v), want %s", got, res.Score, res.Reasons, tc.want)
+			}
+		})
+	}
+}
+
+// A review accepted once is held when posted again.
+func TestPipelineObserve(t *testing.T) {
+	p := &Pipeline{Checks: []Check{Length{Max: 200}, NewDuplicates(10)}, Threshold: 1}
+	const c = "The lamb was tender and the wine list was excellent"
+	res, err := p.Run(c)
+	if err != nil || p.Suspicious(res) {
+		t.Fatalf("first post = %+v, %v", res, err)
+	}
+	p.Observe(c)
+	if res, err := p.Run(c); err != nil || !p.Suspicious(res) {
+		t.Fatalf("repeat = %+v, %v; want it held", res, err)
+	}
+}
+
+// ----- Support function -----
+
+func reasons(t *testing.T, c Check, comment string) []string {
+	t.Helper()
+	findings, err := c.Inspect(comment)
+	if err != nil {
+		t.Fatalf("Inspect(%q): %v", comment, err)
+	}
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Reason)
+	}
+	return out
+}
@@ -1,39 +1,497 @@
 package review
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+	"github.com/ChristopherLeo15/opentable/review/internal/contentfilter"
+	"github.com/ChristopherLeo15/opentable/review/internal/search"
+	resgw "github.com/ChristopherLeo15/opentable/review/internal/gateway/reservation/http"
+	restgw "github.com/ChristopherLeo15/opentable/review/internal/gateway/restaurant/http"
 	m "github.com/ChristopherLeo15/opentable/review/internal/model"
 )
 
+const tracerScope = "review/controller"
+
 // Interface for saving and retrieving reviews.
 type Store interface {
-	Create(x m.Review) m.Review
+	Create(x m.Review) (m.Review, error)
+	GetByID(id int) (m.Review, error)
+	Update(id int, fn func(*m.Review) error) (m.Review, error)
 	ListByRestaurant(restaurantID int) []m.Review
+	All() []m.Review
+}
+
+// Interface for looking up restaurants in the restaurant service.
+type RestaurantGateway interface {
+	GetByID(ctx context.Context, id int) (restgw.Restaurant, error)
+	List(ctx context.Context) ([]restgw.Restaurant, error)
+}
+
+// Interface for looking up reservations to verify diners.
+type ReservationGateway interface {
+	GetByID(ctx context.Context, id int) (resgw.Reservation, error)
 }
 
 type Controller struct {
-	s Store
+	s    Store
+	rg   RestaurantGateway
+	resv ReservationGateway
+	mode integrity.Mode
+	// Decides whether new reviews go live or wait for a moderator
+	publish PublishPolicy
+	// Scores comments; suspicious ones are held for moderation
+	content *contentfilter.Pipeline
+	photos  PhotoConfig
+	// Full-text index over comments, kept in sync on every write
+	index *search.Index
+}
+
+func New(s Store, rg RestaurantGateway, resv ReservationGateway, mode integrity.Mode, publish PublishPolicy, content *contentfilter.Pipeline, photos PhotoConfig) *Controller {
+	c := &Controller{s: s, rg: rg, resv: resv, mode: mode, publish: publish, content: content, photos: photos, index: search.NewIndex()}
+	for _, r := range s.All() {
+		c.reindex(r)
+	}
+	return c
+}
+
+// Sort orders for ListFor.
+const (
+	SortOldest   = ""
+	SortRelevant = "relevant"
+)
+
+// ListOptions narrows and orders ListFor; the zero value lists published
+// reviews oldest first.
+type ListOptions struct {
+	Status m.Status
+	Sort   string
 }
 
-func New(s Store) *Controller { return &Controller{s: s} }
+// ListFor returns the reviews of a restaurant in the given status (published
+// when empty). Only moderators may list other statuses or see the
+// moderation history.
+func (c *Controller) ListFor(ctx context.Context, restaurantID int, opts ListOptions) ([]m.Review, error) {
+	if restaurantID <= 0 {
+		return nil, apperr.Field("restaurant_id", "must be positive")
+	}
+	if opts.Sort != SortOldest && opts.Sort != SortRelevant {
+		return nil, apperr.Field("sort", "must be relevant or empty")
+	}
+	status := opts.Status
+	if status == "" {
+		status = m.StatusPublished
+	}
+	moderator := canModerate(ctx)
+	if status != m.StatusPublished && !moderator {
+		return nil, apperr.Forbidden("only moderators can list %s reviews", status)
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "store.ListByRestaurant", attribute.Int("restaurant.id", restaurantID))
+	out := filter(c.s.ListByRestaurant(restaurantID), status, moderator)
+	span.End()
+	if opts.Sort == SortRelevant {
+		sortByRelevance(out, time.Now())
+	}
+	c.signPhotos(out)
+	return out, nil
+}
 
-func (c *Controller) ListFor(restaurantID int) []m.Review {
+// Summary aggregates the ratings of a restaurant's published reviews.
+func (c *Controller) Summary(ctx context.Context, restaurantID int) (m.Summary, error) {
 	if restaurantID <= 0 {
+		return m.Summary{}, apperr.Field("restaurant_id", "must be positive")
+	}
+	_, span := tracing.Start(ctx, tracerScope, "store.ListByRestaurant", attribute.Int("restaurant.id", restaurantID))
+	defer span.End()
+	return m.Summarize(restaurantID, filter(c.s.ListByRestaurant(restaurantID), m.StatusPublished, false)), nil
+}
+
+// MaxSummaries caps the restaurants one Summaries call covers.
+const MaxSummaries = 100
+
+// Summaries is Summary for several restaurants in one call, in the order
+// asked; restaurants without reviews get empty summaries.
+func (c *Controller) Summaries(ctx context.Context, restaurantIDs []int) ([]m.Summary, error) {
+	if len(restaurantIDs) == 0 {
+		return nil, apperr.Field("restaurant_id", "is required")
+	}
+	if len(restaurantIDs) > MaxSummaries {
+		return nil, apperr.Field("restaurant_id", fmt.Sprintf("at most %d restaurants per call", MaxSummaries))
+	}
+	_, span := tracing.Start(ctx, tracerScope, "store.ListByRestaurant", attribute.Int("restaurant.count", len(restaurantIDs)))
+	defer span.End()
+	out := make([]m.Summary, 0, len(restaurantIDs))
+	for _, id := range restaurantIDs {
+		if id <= 0 {
+			return nil, apperr.Field("restaurant_id", "must be positive")
+		}
+		out = append(out, m.Summarize(id, filter(c.s.ListByRestaurant(id), m.StatusPublished, false)))
+	}
+	return out, nil
+}
+
+// Queue lists reviews in the given status (pending when empty) across all
+// restaurants, oldest first, for moderators.
+func (c *Controller) Queue(ctx context.Context, status m.Status) ([]m.Review, error) {
+	if _, err := authz.Authorize(ctx, authz.ReviewModerate); err != nil {
+		return nil, err
+	}
+	if status == "" {
+		status = m.StatusPending
+	}
+	_, span := tracing.Start(ctx, tracerScope, "store.All")
+	out := filter(c.s.All(), status, true)
+	span.End()
+	c.signPhotos(out)
+	return out, nil
+}
+
+// Moderate moves a review to another status, recording who did it and why.
+func (c *Controller) Moderate(ctx context.Context, id int, to m.Status, reason string) (m.Review, error) {
+	p, err := authz.Authorize(ctx, authz.ReviewModerate)
+	if err != nil {
+		return m.Review{}, err
+	}
+	if id <= 0 {
+		return m.Review{}, apperr.Field("review_id", "must be positive")
+	}
+	action := m.ModerationAction{To: to, Reason: reason, ModeratorID: p.Subject, At: time.Now().UTC()}
+	if err := action.Validate(); err != nil {
+		return m.Review{}, err
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "store.Update", attribute.Int("review.id", id))
+	out, err := c.s.Update(id, func(r *m.Review) error {
+		if !r.Status.CanMoveTo(to) {
+			return apperr.Conflict("review %d cannot move from %s to %s", id, r.Status, to)
+		}
+		action.From = r.Status
+		r.Status = to
+		r.Moderation = append(r.Moderation, action)
 		return nil
+	})
+	tracing.End(span, err)
+	if err == nil {
+		c.reindex(out)
 	}
-	return c.s.ListByRestaurant(restaurantID)
+	return c.present(ctx, out), err
 }
 
-func (c *Controller) Create(r m.Review) (m.Review, error) {
+func (c *Controller) Create(ctx context.Context, r m.Review) (m.Review, error) {
+	p, err := authz.Authorize(ctx, authz.ReviewCreate)
+	if err != nil {
+		return m.Review{}, err
+	}
+	r.AuthorID = p.Subject
+	r.CreatedAt = time.Now().UTC()
+	r.Moderation, r.Flags, r.Response = nil, nil, nil
+	r.Helpful, r.NotHelpful, r.Votes = 0, 0, nil
+	r.Photos = nil
+	r.Comment = strings.TrimSpace(r.Comment)
+	// Clients sending only sub-ratings get their mean as the overall rating
+	if r.Rating == 0 && r.SubRatings != nil {
+		r.Rating = r.SubRatings.Mean()
+	}
+
 	// Simple validation
 	if err := r.Validate(); err != nil {
 		return m.Review{}, err
 	}
 
-	out := c.s.Create(r)
+	// Content filter: hard limits reject, suspicious content is held
+	_, span := tracing.Start(ctx, tracerScope, "contentfilter.Run")
+	verdict, err := c.content.Run(r.Comment)
+	span.SetAttributes(attribute.Float64("contentfilter.score", verdict.Score))
+	tracing.End(span, err)
+	if err != nil {
+		return m.Review{}, err
+	}
+
+	// Referenced restaurant must exist
+	rest, restErr := c.rg.GetByID(ctx, r.RestaurantID)
+	if err := c.mode.Check(restErr, "restaurant_id"); err != nil {
+		return m.Review{}, err
+	}
+
+	if err := c.verify(ctx, &r); err != nil {
+		return m.Review{}, err
+	}
+	switch {
+	case r.Verified:
+	case restErr != nil:
+		// Lenient mode let the write through without the restaurant; its
+		// policy is unknown, so only verified reviews get in meanwhile
+		return m.Review{}, apperr.Unavailable(restErr, "restaurant service unavailable; only verified reviews are accepted until it is back")
+	case rest.VerifiedReviewsOnly:
+		return m.Review{}, apperr.Forbidden("restaurant %d only accepts reviews from verified diners", r.RestaurantID)
+	}
+
+	r.Status = c.publish(r)
+	if c.content.Suspicious(verdict) {
+		r.Status = m.StatusPending
+		r.Flags = verdict.Reasons
+	}
+
+	_, span = tracing.Start(ctx, tracerScope, "store.Create", attribute.Int("restaurant.id", r.RestaurantID))
+	out, err := c.s.Create(r)
+	tracing.End(span, err)
+	if err != nil {
+		return m.Review{}, err
+	}
 	if out.ID <= 0 {
-		return m.Review{}, fmt.Errorf("failed to create review")
+		return m.Review{}, &apperr.Error{Kind: apperr.KindInternal, Msg: "failed to create review"}
 	}
-	return out, nil
+	c.content.Observe(out.Comment)
+	c.reindex(out)
+	return c.present(ctx, out), nil
+}
+
+// verify marks r as a verified-diner review when its reservation shows the
+// author completed a visit to the restaurant. That a reservation backs only
+// one review is enforced by the store on Create.
+func (c *Controller) verify(ctx context.Context, r *m.Review) error {
+	r.Verified = false
+	if r.ReservationID == 0 {
+		return nil
+	}
+
+	ctx, span := tracing.Start(ctx, tracerScope, "reservation.GetByID", attribute.Int("reservation.id", r.ReservationID))
+	res, err := c.resv.GetByID(ctx, r.ReservationID)
+	tracing.End(span, err)
+	if err := c.mode.Check(err, "reservation_id"); err != nil {
+		return err
+	}
+	if err != nil {
+		// Lenient mode: keep the review, unverified
+		return nil
+	}
+
+	switch {
+	case res.DinerID != r.AuthorID:
+		return apperr.Field("reservation_id", "belongs to another diner")
+	case res.RestaurantID != r.RestaurantID:
+		return apperr.Field("reservation_id", "is for another restaurant")
+	case res.Status != resgw.StatusCompleted:
+		return apperr.Field("reservation_id", "visit is not completed")
+	}
+	r.Verified = true
+	return nil
+}
+
+// Vote records the caller's helpful / not helpful vote on a published
+// review, replacing their earlier vote. Authors can't vote on their own.
+func (c *Controller) Vote(ctx context.Context, reviewID int, helpful bool) (m.Review, error) {
+	p, err := authz.Authorize(ctx, authz.ReviewVote)
+	if err != nil {
+		return m.Review{}, err
+	}
+	if reviewID <= 0 {
+		return m.Review{}, apperr.Field("review_id", "must be positive")
+	}
+	_, span := tracing.Start(ctx, tracerScope, "store.Update", attribute.Int("review.id", reviewID))
+	out, err := c.s.Update(reviewID, func(r *m.Review) error {
+		if r.Status != m.StatusPublished {
+			return apperr.NotFound("review %d not found", r.ID)
+		}
+		if r.AuthorID == p.Subject {
+			return apperr.Forbidden("cannot vote on your own review")
+		}
+		r.Vote(p.Subject, helpful)
+		return nil
+	})
+	tracing.End(span, err)
+	return c.present(ctx, out), err
+}
+
+// Unvote withdraws the caller's vote on a review.
+func (c *Controller) Unvote(ctx context.Context, reviewID int) error {
+	p, err := authz.Authorize(ctx, authz.ReviewVote)
+	if err != nil {
+		return err
+	}
+	if reviewID <= 0 {
+		return apperr.Field("review_id", "must be positive")
+	}
+	_, span := tracing.Start(ctx, tracerScope, "store.Update", attribute.Int("review.id", reviewID))
+	_, err = c.s.Update(reviewID, func(r *m.Review) error {
+		if _, ok := r.Votes[p.Subject]; !ok {
+			return apperr.NotFound("no vote on review %d", r.ID)
+		}
+		r.Unvote(p.Subject)
+		return nil
+	})
+	tracing.End(span, err)
+	return err
+}
+
+// Respond sets the owner's reply to a review, creating or replacing it. The
+// caller must own the review's restaurant (checked against the restaurant
+// service) or be an admin.
+func (c *Controller) Respond(ctx context.Context, reviewID int, body string) (m.Review, error) {
+	rev, err := c.ownedReview(ctx, reviewID)
+	if err != nil {
+		return m.Review{}, err
+	}
+	p, _ := auth.FromContext(ctx)
+	now := time.Now().UTC()
+	resp := m.Response{Body: strings.TrimSpace(body), AuthorID: p.Subject, CreatedAt: now, UpdatedAt: now}
+	if err := resp.Validate(); err != nil {
+		return m.Review{}, err
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "store.Update", attribute.Int("review.id", rev.ID))
+	out, err := c.s.Update(rev.ID, func(r *m.Review) error {
+		if r.Status == m.StatusRemoved {
+			return apperr.Conflict("review %d was removed", r.ID)
+		}
+		if r.Response != nil {
+			resp.CreatedAt = r.Response.CreatedAt
+		}
+		r.Response = &resp
+		return nil
+	})
+	tracing.End(span, err)
+	return c.present(ctx, out), err
+}
+
+// DeleteResponse removes the owner's reply to a review.
+func (c *Controller) DeleteResponse(ctx context.Context, reviewID int) error {
+	rev, err := c.ownedReview(ctx, reviewID)
+	if err != nil {
+		return err
+	}
+	_, span := tracing.Start(ctx, tracerScope, "store.Update", attribute.Int("review.id", rev.ID))
+	_, err = c.s.Update(rev.ID, func(r *m.Review) error {
+		if r.Response == nil {
+			return apperr.NotFound("review %d has no response", r.ID)
+		}
+		r.Response = nil
+		return nil
+	})
+	tracing.End(span, err)
+	return err
+}
+
+// ownedReview loads a review and checks the caller owns its restaurant.
+func (c *Controller) ownedReview(ctx context.Context, reviewID int) (m.Review, error) {
+	if _, err := authz.Authorize(ctx, authz.RestaurantManage); err != nil {
+		return m.Review{}, err
+	}
+	if reviewID <= 0 {
+		return m.Review{}, apperr.Field("review_id", "must be positive")
+	}
+	rev, err := c.s.GetByID(reviewID)
+	if err != nil {
+		return m.Review{}, err
+	}
+
+	ctx, span := tracing.Start(ctx, tracerScope, "restaurant.GetByID", attribute.Int("restaurant.id", rev.RestaurantID))
+	rest, err := c.rg.GetByID(ctx, rev.RestaurantID)
+	tracing.End(span, err)
+	if err != nil {
+		// Ownership can't be proven without the restaurant service
+		if apperr.Is(err, apperr.KindNotFound) {
+			return m.Review{}, apperr.Conflict("restaurant %d of review %d no longer exists", rev.RestaurantID, rev.ID)
+		}
+		return m.Review{}, err
+	}
+	if _, err := authz.AuthorizeOwner(ctx, authz.RestaurantManage, rest.OwnerID); err != nil {
+		return m.Review{}, err
+	}
+	return rev, nil
+}
+
+// ScanOrphans lists reviews whose restaurant_id no longer resolves.
+func (c *Controller) ScanOrphans(ctx context.Context) integrity.Report {
+	all := c.s.All()
+	rep := integrity.Report{GeneratedAt: time.Now().UTC(), Checked: len(all), Orphans: []integrity.Orphan{}}
+
+	rests, err := c.rg.List(ctx)
+	if err != nil {
+		rep.Unchecked = len(all)
+		return rep
+	}
+	known := make(map[int]bool, len(rests))
+	for _, r := range rests {
+		known[r.ID] = true
+	}
+	for _, v := range all {
+		if !known[v.RestaurantID] {
+			rep.Orphans = append(rep.Orphans, integrity.Orphan{ID: v.ID, Field: "restaurant_id", RefID: v.RestaurantID})
+		}
+	}
+	return rep
+}
+
+// reindex keeps the search index in step with a stored review. Only
+// published reviews are indexed, so pending and hidden ones neither skew
+// the term statistics nor take up result slots; Moderate calls it on every
+// status change.
+func (c *Controller) reindex(r m.Review) {
+	if r.Status != m.StatusPublished || r.Comment == "" {
+		c.index.Remove(r.ID)
+		return
+	}
+	c.index.Put(r.ID, r.RestaurantID, r.Comment)
+}
+
+// present prepares r for the caller: moderation details are dropped unless
+// they are a moderator, and photo URLs are signed.
+func (c *Controller) present(ctx context.Context, r m.Review) m.Review {
+	// Don't tell spammers which rule caught them
+	if !canModerate(ctx) {
+		r.Moderation, r.Flags = nil, nil
+	}
+	rs := []m.Review{r}
+	c.signPhotos(rs)
+	return rs[0]
+}
+
+// signPhotos fills in signed photo URLs. Photos slices are copied first so
+// the store's records are never written to.
+func (c *Controller) signPhotos(rs []m.Review) {
+	now := time.Now()
+	for i := range rs {
+		if len(rs[i].Photos) == 0 {
+			continue
+		}
+		rs[i].Photos = slices.Clone(rs[i].Photos)
+		for j := range rs[i].Photos {
+			ph := &rs[i].Photos[j]
+			ph.URL = c.photos.Signer.URL(ph.Key, now)
+			ph.ThumbURL = c.photos.Signer.URL(ph.ThumbKey, now)
+		}
+	}
+}
+
+func canModerate(ctx context.Context) bool {
+	p, ok := auth.FromContext(ctx)
+	return ok && authz.Can(p.Roles, authz.ReviewModerate)
+}
+
+// filter keeps reviews in status; the moderation history and filter flags
+// are dropped unless the caller is a moderator.
+func filter(all []m.Review, status m.Status, moderator bool) []m.Review {
+	out := make([]m.Review, 0, len(all))
+	for _, r := range all {
+		if r.Status != status {
+			continue
+		}
+		if !moderator {
+			r.Moderation, r.Flags = nil, nil
+		}
+		out = append(out, r)
+	}
+	return out
 }
\ No newline at end of file
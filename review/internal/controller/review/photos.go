@@ -0,0 +1,207 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+	"github.com/ChristopherLeo15/opentable/review/internal/blob"
+	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+	"github.com/ChristopherLeo15/opentable/review/internal/photo"
+)
+
+// PhotoConfig wires review photos to their storage.
+type PhotoConfig struct {
+	Store        blob.BlobStore
+	Signer       *photo.Signer
+	MaxBytes     int64
+	MaxPerReview int
+}
+
+// MaxUploadBytes bounds one upload request: a full review's worth of photos
+// plus room for the multipart framing.
+func (c *Controller) MaxUploadBytes() int64 {
+	return c.photos.MaxBytes*int64(c.photos.MaxPerReview) + 1<<20
+}
+
+// AddPhotos attaches every image next yields to a review, or none of them:
+// if any image is invalid or can't be stored, the blobs stored so far are
+// removed again. next returns io.EOF after the last image. Only the review's
+// author (or an admin) may add photos. Each image is re-encoded without
+// metadata and a thumbnail is stored next to it.
+func (c *Controller) AddPhotos(ctx context.Context, reviewID int, next func() (io.Reader, error)) (m.Review, error) {
+	rev, err := c.authoredReview(ctx, reviewID)
+	if err != nil {
+		return m.Review{}, err
+	}
+
+	var stored []m.Photo
+	for {
+		r, err := next()
+		if err == io.EOF {
+			break
+		}
+		var ph m.Photo
+		if err == nil {
+			ph, err = c.storePhoto(ctx, reviewID, len(rev.Photos)+len(stored), r)
+		}
+		if err != nil {
+			c.deleteBlobs(ctx, stored...)
+			return m.Review{}, err
+		}
+		stored = append(stored, ph)
+	}
+	if len(stored) == 0 {
+		return m.Review{}, apperr.Field("photo", "is required")
+	}
+
+	out, err := c.s.Update(reviewID, func(r *m.Review) error {
+		if len(r.Photos)+len(stored) > c.photos.MaxPerReview {
+			return apperr.Conflict("review %d can have at most %d photos", reviewID, c.photos.MaxPerReview)
+		}
+		r.Photos = append(r.Photos, stored...)
+		return nil
+	})
+	if err != nil {
+		c.deleteBlobs(ctx, stored...)
+		return m.Review{}, err
+	}
+	return c.present(ctx, out), nil
+}
+
+// storePhoto processes one upload and stores it with its thumbnail; have is
+// how many photos the review would already hold.
+func (c *Controller) storePhoto(ctx context.Context, reviewID, have int, r io.Reader) (m.Photo, error) {
+	if have >= c.photos.MaxPerReview {
+		return m.Photo{}, apperr.Conflict("review %d can have at most %d photos", reviewID, c.photos.MaxPerReview)
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "photo.Process", attribute.Int("review.id", reviewID))
+	img, err := photo.Process(r, c.photos.MaxBytes)
+	tracing.End(span, err)
+	if err != nil {
+		return m.Photo{}, err
+	}
+
+	id, err := newPhotoID()
+	if err != nil {
+		return m.Photo{}, err
+	}
+	dir := strconv.Itoa(reviewID)
+	ph := m.Photo{
+		ID:          id,
+		ContentType: img.ContentType,
+		Width:       img.Width,
+		Height:      img.Height,
+		Size:        len(img.Full),
+		Key:         path.Join(dir, id+"."+img.Ext),
+		ThumbKey:    path.Join(dir, id+"_thumb."+img.Ext),
+		UploadedAt:  time.Now().UTC(),
+	}
+
+	ctx, span = tracing.Start(ctx, tracerScope, "blob.Put", attribute.String("photo.id", id))
+	err = c.putBlobs(ctx, ph, img)
+	tracing.End(span, err)
+	if err != nil {
+		return m.Photo{}, apperr.Unavailable(err, "photo storage unavailable")
+	}
+	return ph, nil
+}
+
+// DeletePhoto removes a photo from a review and from storage.
+func (c *Controller) DeletePhoto(ctx context.Context, reviewID int, photoID string) error {
+	if _, err := c.authoredReview(ctx, reviewID); err != nil {
+		return err
+	}
+	var removed m.Photo
+	_, err := c.s.Update(reviewID, func(r *m.Review) error {
+		for i, ph := range r.Photos {
+			if ph.ID == photoID {
+				removed = ph
+				r.Photos = append(r.Photos[:i], r.Photos[i+1:]...)
+				return nil
+			}
+		}
+		return apperr.NotFound("photo %s not found", photoID)
+	})
+	if err != nil {
+		return err
+	}
+	c.deleteBlobs(ctx, removed)
+	return nil
+}
+
+// OpenPhoto returns a photo blob for a signed URL.
+func (c *Controller) OpenPhoto(ctx context.Context, key string, q url.Values) (io.ReadCloser, string, error) {
+	if !c.photos.Signer.Verify(key, q, time.Now()) {
+		return nil, "", apperr.Forbidden("invalid or expired photo link")
+	}
+	rc, err := c.photos.Store.Get(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	ct := "image/jpeg"
+	if path.Ext(key) == ".png" {
+		ct = "image/png"
+	}
+	return rc, ct, nil
+}
+
+// authoredReview loads a review the caller wrote (admins may act on any).
+func (c *Controller) authoredReview(ctx context.Context, reviewID int) (m.Review, error) {
+	p, err := authz.Authorize(ctx, authz.ReviewCreate)
+	if err != nil {
+		return m.Review{}, err
+	}
+	if reviewID <= 0 {
+		return m.Review{}, apperr.Field("review_id", "must be positive")
+	}
+	rev, err := c.s.GetByID(reviewID)
+	if err != nil {
+		return m.Review{}, err
+	}
+	if rev.AuthorID != p.Subject && !authz.IsAdmin(p) {
+		return m.Review{}, apperr.Forbidden("not the author of review %d", reviewID)
+	}
+	if rev.Status == m.StatusRemoved {
+		return m.Review{}, apperr.Conflict("review %d was removed", reviewID)
+	}
+	return rev, nil
+}
+
+func (c *Controller) putBlobs(ctx context.Context, ph m.Photo, img photo.Processed) error {
+	if err := c.photos.Store.Put(ctx, ph.Key, bytes.NewReader(img.Full)); err != nil {
+		return err
+	}
+	if err := c.photos.Store.Put(ctx, ph.ThumbKey, bytes.NewReader(img.Thumb)); err != nil {
+		_ = c.photos.Store.Delete(ctx, ph.Key)
+		return err
+	}
+	return nil
+}
+
+func (c *Controller) deleteBlobs(ctx context.Context, phs ...m.Photo) {
+	for _, ph := range phs {
+		_ = c.photos.Store.Delete(ctx, ph.Key)
+		_ = c.photos.Store.Delete(ctx, ph.ThumbKey)
+	}
+}
+
+func newPhotoID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
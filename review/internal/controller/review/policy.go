@@ -0,0 +1,50 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+)
+
+// PublishPolicy decides the status a new review starts in.
+type PublishPolicy func(r m.Review) m.Status
+
+// PublishAlways publishes every review immediately.
+func PublishAlways(m.Review) m.Status { return m.StatusPublished }
+
+// PublishNever queues every review for a moderator.
+func PublishNever(m.Review) m.Status { return m.StatusPending }
+
+// PublishMinRating publishes reviews rated at least min and queues the rest,
+// since low ratings are where abuse usually shows up.
+func PublishMinRating(min int) PublishPolicy {
+	return func(r m.Review) m.Status {
+		if r.Rating >= min {
+			return m.StatusPublished
+		}
+		return m.StatusPending
+	}
+}
+
+// PolicyFromEnv reads the policy from key: "always" (default), "never" or
+// "min-rating:N".
+func PolicyFromEnv(key string) (PublishPolicy, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	switch {
+	case v == "" || v == "always":
+		return PublishAlways, nil
+	case v == "never":
+		return PublishNever, nil
+	case strings.HasPrefix(v, "min-rating:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(v, "min-rating:"))
+		if err != nil || n < 1 || n > 5 {
+			return nil, fmt.Errorf("%s: min-rating must be between 1 and 5", key)
+		}
+		return PublishMinRating(n), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown policy %q", key, v)
+	}
+}
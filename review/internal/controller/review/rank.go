@@ -0,0 +1,66 @@
+package review
+
+import (
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+)
+
+// Relevance blends helpfulness, recency and comment length. Weights sum
+// to 1 so scores stay in [0, 1].
+const (
+	weightHelpful = 0.5
+	weightRecency = 0.3
+	weightLength  = 0.2
+
+	// A review loses half its recency score every halfLife
+	halfLife = 90 * 24 * time.Hour
+	// Comments this long (in characters) get the full length score
+	fullLength = 500
+)
+
+// sortByRelevance orders reviews by relevance, most relevant first. Scores are
+// computed once per review, so ranking is O(n log n) with cheap compares.
+func sortByRelevance(reviews []m.Review, now time.Time) {
+	scores := make([]float64, len(reviews))
+	idx := make([]int, len(reviews))
+	for i, r := range reviews {
+		scores[i] = relevance(r, now)
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return scores[idx[a]] > scores[idx[b]] })
+
+	sorted := make([]m.Review, len(reviews))
+	for i, j := range idx {
+		sorted[i] = reviews[j]
+	}
+	copy(reviews, sorted)
+}
+
+func relevance(r m.Review, now time.Time) float64 {
+	helpful := wilson(r.Helpful, r.Helpful+r.NotHelpful)
+
+	recency := 1.0
+	if age := now.Sub(r.CreatedAt); age > 0 {
+		recency = math.Exp2(-float64(age) / float64(halfLife))
+	}
+
+	length := math.Min(1, math.Log1p(float64(utf8.RuneCountInString(r.Comment)))/math.Log1p(fullLength))
+
+	return weightHelpful*helpful + weightRecency*recency + weightLength*length
+}
+
+// wilson is the lower bound of the 95% Wilson score interval for pos/n, so
+// 3/3 helpful ranks below 90/100.
+func wilson(pos, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	const z = 1.96
+	p := float64(pos) / float64(n)
+	nf := float64(n)
+	return (p + z*z/(2*nf) - z*math.Sqrt((p*(1-p)+z*z/(4*nf))/nf)) / (1 + z*z/nf)
+}
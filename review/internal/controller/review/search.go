@@ -0,0 +1,54 @@
+package review
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+	"github.com/ChristopherLeo15/opentable/review/internal/search"
+)
+
+// SearchResult is a published review matching a search, with its score.
+type SearchResult struct {
+	Score  float64  `json:"score"`
+	Review m.Review `json:"review"`
+}
+
+// Search finds published reviews whose comment matches q: every word
+// (stemmed, so "dined" finds "dining") and every "quoted phrase" must
+// appear. restaurantID narrows the search when positive.
+func (c *Controller) Search(ctx context.Context, q string, restaurantID, limit int) ([]SearchResult, error) {
+	query := search.ParseQuery(q)
+	if query.Empty() {
+		return nil, apperr.Field("q", "must contain at least one searchable word")
+	}
+	if restaurantID < 0 {
+		return nil, apperr.Field("restaurant_id", "must be positive")
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	_, span := tracing.Start(ctx, tracerScope, "index.Search", attribute.Int("restaurant.id", restaurantID))
+	hits := c.index.Search(query, restaurantID)
+	span.SetAttributes(attribute.Int("search.hits", len(hits)))
+	span.End()
+
+	out := make([]SearchResult, 0, min(limit, len(hits)))
+	for _, h := range hits {
+		if len(out) == limit {
+			break
+		}
+		// The index holds published reviews only; this covers a status
+		// change racing the search
+		r, err := c.s.GetByID(h.ID)
+		if err != nil || r.Status != m.StatusPublished {
+			continue
+		}
+		out = append(out, SearchResult{Score: h.Score, Review: c.present(ctx, r)})
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package review
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/authz"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+	"github.com/ChristopherLeo15/opentable/review/internal/repository/memory"
+	"github.com/ChristopherLeo15/opentable/review/internal/search"
+)
+
+// Only published reviews are in the index, and moderation moves reviews in
+// and out of it.
+func TestIndexFollowsPublishedStatus(t *testing.T) {
+	s := memory.New()
+	for _, st := range []m.Status{m.StatusPublished, m.StatusPending, m.StatusHidden} {
+		if _, err := s.Create(m.Review{RestaurantID: 1, Rating: 4, Comment: "pasta and wine", Status: st}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := New(s, nil, nil, integrity.Strict, nil, nil, PhotoConfig{})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "mod", Roles: []string{string(authz.RoleModerator)}})
+
+	indexed := func() []int {
+		var ids []int
+		for _, h := range c.index.Search(search.ParseQuery("pasta"), 0) {
+			ids = append(ids, h.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	if got := indexed(); !slices.Equal(got, []int{1}) {
+		t.Fatalf("indexed at start = %v, want [1]", got)
+	}
+
+	steps := []struct {
+		id     int
+		to     m.Status
+		reason string
+		want   []int
+	}{
+		{2, m.StatusPublished, "", []int{1, 2}},
+		{1, m.StatusHidden, "off topic", []int{2}},
+		{3, m.StatusPublished, "", []int{2, 3}},
+		{2, m.StatusRemoved, "spam", []int{3}},
+	}
+	for _, st := range steps {
+		if _, err := c.Moderate(ctx, st.id, st.to, st.reason); err != nil {
+			t.Fatalf("Moderate(%d, %s): %v", st.id, st.to, err)
+		}
+		if got := indexed(); !slices.Equal(got, st.want) {
+			t.Fatalf("after moving %d to %s, indexed = %v, want %v", st.id, st.to, got, st.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/discovery"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// Reservation statuses the restaurant service reports.
+const (
+	StatusBooked    = "booked"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusNoShow    = "no_show"
+)
+
+// Reservation is the subset of a reservation record reviews need, served by
+// the restaurant service at GET /reservations?id=. The local package serves
+// the same records from a file.
+type Reservation struct {
+	ID           int    `json:"id"`
+	RestaurantID int    `json:"restaurant_id"`
+	DinerID      string `json:"diner_id"`
+	Status       string `json:"status"`
+}
+
+// Gateway discovers the restaurant service, which holds reservations, via
+// Consul.
+type Gateway struct {
+	client   *http.Client
+	resolver *discovery.Resolver
+}
+
+func New() *Gateway {
+	tr := &http.Transport{
+		DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		IdleConnTimeout:       30 * time.Second,
+		MaxIdleConns:          10,
+	}
+	consul := &http.Client{Transport: tracing.Transport(tr)}
+	client := &http.Client{Transport: auth.ServiceTransport(consul.Transport)}
+	return &Gateway{client: client, resolver: discovery.NewResolver("restaurant", consul)}
+}
+
+func (g *Gateway) GetByID(ctx context.Context, id int) (Reservation, error) {
+	if _, has := ctx.Deadline(); !has {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+	base, err := g.resolver.BaseURL(ctx)
+	if err != nil {
+		return Reservation{}, apperr.Unavailable(err, "restaurant service unavailable")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/reservations?id=%d", base, id), nil)
+	if err != nil {
+		return Reservation{}, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return Reservation{}, apperr.Unavailable(err, "restaurant service unavailable")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Reservation{}, apperr.NotFound("reservation %d not found", id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Reservation{}, apperr.Unavailable(fmt.Errorf("reservation %d -> %d", id, resp.StatusCode), "restaurant service unavailable")
+	}
+	var out Reservation
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return Reservation{}, apperr.Unavailable(err, "restaurant service unavailable")
+	}
+	return out, nil
+}
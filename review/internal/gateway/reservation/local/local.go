@@ -0,0 +1,76 @@
+package local
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	resgw "github.com/ChristopherLeo15/opentable/review/internal/gateway/reservation/http"
+)
+
+// Gateway serves reservations from a fixed table. It stands in for the
+// restaurant service's reservations so that verified reviews can be tried
+// without booking through it.
+type Gateway struct {
+	byID map[int]resgw.Reservation
+}
+
+// Open loads a CSV file of id,restaurant_id,diner_id,status rows.
+func Open(path string) (*Gateway, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+func parse(r io.Reader) (*Gateway, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 4
+	g := &Gateway{byID: make(map[int]resgw.Reservation)}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return g, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		id, err1 := strconv.Atoi(strings.TrimSpace(rec[0]))
+		restaurantID, err2 := strconv.Atoi(strings.TrimSpace(rec[1]))
+		if err1 != nil || err2 != nil || id <= 0 || restaurantID <= 0 {
+			return nil, fmt.Errorf("reservation data line %d: bad id", line)
+		}
+		res := resgw.Reservation{
+			ID:           id,
+			RestaurantID: restaurantID,
+			DinerID:      strings.TrimSpace(rec[2]),
+			Status:       strings.TrimSpace(rec[3]),
+		}
+		switch res.Status {
+		case resgw.StatusBooked, resgw.StatusCompleted, resgw.StatusCancelled, resgw.StatusNoShow:
+		default:
+			return nil, fmt.Errorf("reservation data line %d: unknown status %q", line, res.Status)
+		}
+		if _, dup := g.byID[id]; dup {
+			return nil, fmt.Errorf("reservation data line %d: duplicate id %d", line, id)
+		}
+		g.byID[id] = res
+	}
+}
+
+func (g *Gateway) GetByID(_ context.Context, id int) (resgw.Reservation, error) {
+	res, ok := g.byID[id]
+	if !ok {
+		return resgw.Reservation{}, apperr.NotFound("reservation %d not found", id)
+	}
+	return res, nil
+}
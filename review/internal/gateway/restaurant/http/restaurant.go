@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/auth"
+	"github.com/ChristopherLeo15/opentable/pkg/discovery"
+	"github.com/ChristopherLeo15/opentable/pkg/tracing"
+)
+
+// Restaurant is the subset of the restaurant service's record reviews need.
+type Restaurant struct {
+	ID          int    `json:"id"`
+	MetadataID  int    `json:"metadata_id"`
+	DisplayName string `json:"display_name"`
+	// Only the owner may respond to the restaurant's reviews
+	OwnerID string `json:"owner_id"`
+	// Reviews need a completed reservation when set
+	VerifiedReviewsOnly bool `json:"verified_reviews_only"`
+}
+
+// Gateway discovers the restaurant service via Consul.
+type Gateway struct {
+	client   *http.Client
+	resolver *discovery.Resolver
+}
+
+func New() *Gateway {
+	tr := &http.Transport{
+		DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		IdleConnTimeout:       30 * time.Second,
+		MaxIdleConns:          10,
+	}
+	consul := &http.Client{Transport: tracing.Transport(tr)}
+	client := &http.Client{Transport: auth.ServiceTransport(consul.Transport)}
+	return &Gateway{client: client, resolver: discovery.NewResolver("restaurant", consul)}
+}
+
+// Cap on single-record responses. Listings grow with the data and the
+// orphan scan needs all of them, so List reads them uncapped.
+const maxRecordBytes = 1 << 20
+
+// get decodes the JSON response for path into out, reading at most max
+// bytes when max > 0.
+func (g *Gateway) get(ctx context.Context, path string, max int64, out any) error {
+	if _, has := ctx.Deadline(); !has {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+	base, err := g.resolver.BaseURL(ctx)
+	if err != nil {
+		return apperr.Unavailable(err, "restaurant service unavailable")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return apperr.Unavailable(err, "restaurant service unavailable")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return apperr.NotFound("restaurant %s not found", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return apperr.Unavailable(fmt.Errorf("restaurant %s -> %d", path, resp.StatusCode), "restaurant service unavailable")
+	}
+	var body io.Reader = resp.Body
+	if max > 0 {
+		body = io.LimitReader(resp.Body, max)
+	}
+	return json.NewDecoder(body).Decode(out)
+}
+
+func (g *Gateway) GetByID(ctx context.Context, id int) (Restaurant, error) {
+	var out struct {
+		Restaurant Restaurant `json:"restaurant"`
+	}
+	if err := g.get(ctx, fmt.Sprintf("/restaurants?id=%d", id), maxRecordBytes, &out); err != nil {
+		return Restaurant{}, err
+	}
+	return out.Restaurant, nil
+}
+
+// List returns every restaurant; used by the orphan scan.
+func (g *Gateway) List(ctx context.Context) ([]Restaurant, error) {
+	var out []Restaurant
+	if err := g.get(ctx, "/restaurants", 0, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -2,27 +2,54 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	ctrl "github.com/ChristopherLeo15/opentable/review/internal/controller/review"
 	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/integrity"
+	"github.com/ChristopherLeo15/opentable/pkg/metrics"
+	"github.com/ChristopherLeo15/opentable/pkg/problem"
 )
 
+// photoUploadTimeout replaces the server's WriteTimeout for photo uploads,
+// long enough for a full review's worth of photos on a slow uplink.
+const photoUploadTimeout = 5 * time.Minute
+
 type Handler struct {
-	c *ctrl.Controller
+	c       *ctrl.Controller
+	orphans *integrity.Reporter
 }
 
-func New(c *ctrl.Controller) *Handler { return &Handler{c: c} }
+func New(c *ctrl.Controller, orphans *integrity.Reporter) *Handler {
+	return &Handler{c: c, orphans: orphans}
+}
 
-func (h *Handler) Router() http.Handler {
+// Router wires the routes; mw (auth, rate limiting, ...) wraps every route.
+func (h *Handler) Router(mw ...func(http.Handler) http.Handler) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/reviews", h.handleReviews) // GET ?restaurant_id=, POST body
+	mux.HandleFunc("/reviews/summary", h.getSummary)          // GET ?restaurant_id=, rating aggregates
+	mux.HandleFunc("/reviews/summaries", h.getSummaries)      // GET ?restaurant_id=1,2,3 (up to 100)
+	mux.HandleFunc("/reviews/search", h.getSearch)            // GET ?q=&restaurant_id=&limit=
+	mux.HandleFunc("/reviews/moderation", h.handleModeration) // GET queue ?status=, POST action
+	mux.HandleFunc("/reviews/response", h.handleResponse)     // PUT/DELETE ?review_id=, owner only
+	mux.HandleFunc("/reviews/vote", h.handleVote)             // PUT/DELETE ?review_id=, one vote per user
+	mux.HandleFunc("/reviews/photos", h.handlePhotos)         // POST multipart / DELETE ?review_id=, author only
+	mux.HandleFunc("/reviews/photos/", h.getPhoto)            // GET signed blob URL
+	mux.HandleFunc("/orphans", h.getOrphans)
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	return mux
+	mux.Handle("/metrics", metrics.Handler())
+	return metrics.Middleware(mux, mw...)
 }
 
 func (h *Handler) handleReviews(w http.ResponseWriter, r *http.Request) {
@@ -32,41 +59,356 @@ func (h *Handler) handleReviews(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		h.postReview(w, r)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *Handler) getForRestaurant(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("restaurant_id")
 	if q == "" {
-		http.Error(w, "restaurant_id is required", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "restaurant_id is required")
 		return
 	}
 	id, err := strconv.Atoi(q)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid restaurant_id", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "invalid restaurant_id")
+		return
+	}
+	status, ok := parseStatus(w, r)
+	if !ok {
+		return
+	}
+	opts := ctrl.ListOptions{Status: status, Sort: r.URL.Query().Get("sort")}
+	out, err := h.c.ListFor(r.Context(), id, opts)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, h.c.ListFor(id))
+	writeJSON(w, http.StatusOK, out)
 }
 
 func (h *Handler) postReview(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	var in m.Review
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "invalid json body", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
 		return
 	}
-	out, err := h.c.Create(in)
+	out, err := h.c.Create(r.Context(), in)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusCreated, out)
 }
 
+func (h *Handler) getSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("restaurant_id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid restaurant_id")
+		return
+	}
+	out, err := h.c.Summary(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) getSummaries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var ids []int
+	for _, v := range r.URL.Query()["restaurant_id"] {
+		for _, f := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || id <= 0 {
+				problem.Error(w, r, http.StatusBadRequest, "invalid restaurant_id")
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+	out, err := h.c.Summaries(r.Context(), ids)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) getSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	restaurantID := 0
+	if v := q.Get("restaurant_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			problem.Error(w, r, http.StatusBadRequest, "invalid restaurant_id")
+			return
+		}
+		restaurantID = id
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	out, err := h.c.Search(r.Context(), q.Get("q"), restaurantID, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) handleModeration(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getQueue(w, r)
+	case http.MethodPost:
+		h.postModeration(w, r)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) getQueue(w http.ResponseWriter, r *http.Request) {
+	status, ok := parseStatus(w, r)
+	if !ok {
+		return
+	}
+	out, err := h.c.Queue(r.Context(), status)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) postModeration(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var in struct {
+		ReviewID int      `json:"review_id"`
+		Status   m.Status `json:"status"`
+		Reason   string   `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	out, err := h.c.Moderate(r.Context(), in.ReviewID, in.Status, strings.TrimSpace(in.Reason))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) handleResponse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("review_id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid review_id")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		defer r.Body.Close()
+		var in struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+			return
+		}
+		out, err := h.c.Respond(r.Context(), id, in.Body)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodDelete:
+		if err := h.c.DeleteResponse(r.Context(), id); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) handleVote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("review_id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid review_id")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		defer r.Body.Close()
+		var in struct {
+			Helpful *bool `json:"helpful"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			problem.Error(w, r, http.StatusBadRequest, "invalid json body")
+			return
+		}
+		if in.Helpful == nil {
+			problem.Write(w, r, apperr.Field("helpful", "is required"))
+			return
+		}
+		out, err := h.c.Vote(r.Context(), id, *in.Helpful)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodDelete:
+		if err := h.c.Unvote(r.Context(), id); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) handlePhotos(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("review_id"))
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, "invalid review_id")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		h.postPhotos(w, r, id)
+	case http.MethodDelete:
+		if err := h.c.DeletePhoto(r.Context(), id, r.URL.Query().Get("photo_id")); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// postPhotos streams every "photo" part of a multipart body to the
+// controller, which stores all of them or none; the controller enforces the
+// per-photo size limit, the body as a whole is capped here.
+func (h *Handler) postPhotos(w http.ResponseWriter, r *http.Request, reviewID int) {
+	// A full upload takes longer than the server's WriteTimeout allows
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(photoUploadTimeout)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.c.MaxUploadBytes())
+	mr, err := r.MultipartReader()
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "expected a multipart/form-data body")
+		return
+	}
+	var (
+		part    *multipart.Part
+		bodyErr error
+	)
+	next := func() (io.Reader, error) {
+		if part != nil {
+			part.Close()
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				if err != io.EOF {
+					bodyErr = err
+				}
+				return nil, err
+			}
+			if p.FormName() == "photo" {
+				part = p
+				return p, nil
+			}
+			p.Close()
+		}
+	}
+	out, err := h.c.AddPhotos(r.Context(), reviewID, next)
+	if part != nil {
+		part.Close()
+	}
+	switch {
+	case tooLarge(err):
+		problem.Error(w, r, http.StatusRequestEntityTooLarge, "upload is too large")
+	case err != nil && err == bodyErr:
+		problem.Error(w, r, http.StatusBadRequest, "invalid multipart body")
+	case err != nil:
+		problem.Write(w, r, err)
+	default:
+		writeJSON(w, http.StatusCreated, out)
+	}
+}
+
+func (h *Handler) getPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/reviews/photos/")
+	rc, contentType, err := h.c.OpenPhoto(r.Context(), key, r.URL.Query())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	_, _ = io.Copy(w, rc)
+}
+
+// Latest dangling-reference report from the periodic scan
+func (h *Handler) getOrphans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rep, ok := h.orphans.Latest()
+	if !ok {
+		problem.Write(w, r, integrity.ErrNoReport)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
 // ----- Support function -----
 
+// tooLarge reports whether err comes from the http.MaxBytesReader cap.
+func tooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// parseStatus reads the optional ?status= filter.
+func parseStatus(w http.ResponseWriter, r *http.Request) (m.Status, bool) {
+	q := r.URL.Query().Get("status")
+	if q == "" {
+		return "", true
+	}
+	st, ok := m.ParseStatus(q)
+	if !ok {
+		problem.Write(w, r, apperr.Field("status", "must be pending, published, hidden or removed"))
+	}
+	return st, ok
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -1,20 +1,87 @@
 package model
 
-import "fmt"
+import (
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
 
 type Review struct {
-	ID           int    `json:"id"`
-	RestaurantID int    `json:"restaurant_id"`
-	Rating       int    `json:"rating"`
-	Comment      string `json:"comment"`
+	ID           int `json:"id"`
+	RestaurantID int `json:"restaurant_id"`
+	Rating       int `json:"rating"`
+	// Optional food/service/ambience/value ratings next to the overall one
+	SubRatings *SubRatings `json:"sub_ratings,omitempty"`
+	Comment    string      `json:"comment"`
+	// Subject of the diner who wrote the review; set from the caller
+	AuthorID string `json:"author_id"`
+	// Optional reservation proving the author dined there; Verified is set
+	// by the controller once the reservation checks out
+	ReservationID int  `json:"reservation_id,omitempty"`
+	Verified      bool `json:"verified"`
+	// Moderation state; set by the controller, never by the client
+	Status     Status             `json:"status"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Moderation []ModerationAction `json:"moderation,omitempty"`
+	// Content filter reasons a review was held for moderation
+	Flags []string `json:"flags,omitempty"`
+	// Owner's reply, if any
+	Response *Response `json:"response,omitempty"`
+	Photos   []Photo   `json:"photos,omitempty"`
+	// Helpfulness votes; Votes maps voter subject to their vote and is
+	// private, the counts are public
+	Helpful    int             `json:"helpful"`
+	NotHelpful int             `json:"not_helpful"`
+	Votes      map[string]bool `json:"-"`
+}
+
+// Vote records voter's vote (helpful or not), replacing any earlier one.
+func (r *Review) Vote(voter string, helpful bool) {
+	r.Unvote(voter)
+	if r.Votes == nil {
+		r.Votes = make(map[string]bool)
+	}
+	r.Votes[voter] = helpful
+	if helpful {
+		r.Helpful++
+	} else {
+		r.NotHelpful++
+	}
+}
+
+// Unvote removes voter's vote, if any.
+func (r *Review) Unvote(voter string) {
+	prev, ok := r.Votes[voter]
+	if !ok {
+		return
+	}
+	delete(r.Votes, voter)
+	if prev {
+		r.Helpful--
+	} else {
+		r.NotHelpful--
+	}
 }
 
 func (r Review) Validate() error {
+	var fields []apperr.FieldError
+	if r.ID < 0 {
+		fields = append(fields, apperr.FieldError{Field: "id", Message: "must be positive"})
+	}
+	if r.ReservationID < 0 {
+		fields = append(fields, apperr.FieldError{Field: "reservation_id", Message: "must be positive"})
+	}
 	if r.RestaurantID <= 0 {
-		return fmt.Errorf("restaurant_id must be positive")
+		fields = append(fields, apperr.FieldError{Field: "restaurant_id", Message: "must be positive"})
 	}
 	if r.Rating < 1 || r.Rating > 5 {
-		return fmt.Errorf("rating must be between 1 and 5")
+		fields = append(fields, apperr.FieldError{Field: "rating", Message: "must be between 1 and 5"})
+	}
+	if r.SubRatings != nil {
+		fields = append(fields, r.SubRatings.fieldErrors()...)
+	}
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
 	}
 	return nil
-}
\ No newline at end of file
+}
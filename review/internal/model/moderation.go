@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Status is where a review is in the moderation lifecycle. Only published
+// reviews are visible to the public.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusPublished Status = "published"
+	StatusHidden    Status = "hidden"
+	StatusRemoved   Status = "removed"
+)
+
+// transitions lists the statuses a review may move to from each status.
+// Removed is final.
+var transitions = map[Status][]Status{
+	StatusPending:   {StatusPublished, StatusHidden, StatusRemoved},
+	StatusPublished: {StatusHidden, StatusRemoved},
+	StatusHidden:    {StatusPublished, StatusRemoved},
+}
+
+// CanMoveTo reports whether a review in status s may move to next.
+func (s Status) CanMoveTo(next Status) bool {
+	for _, t := range transitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatus returns the Status named by s.
+func ParseStatus(s string) (Status, bool) {
+	switch st := Status(s); st {
+	case StatusPending, StatusPublished, StatusHidden, StatusRemoved:
+		return st, true
+	}
+	return "", false
+}
+
+// ModerationAction records one status change.
+type ModerationAction struct {
+	From        Status    `json:"from"`
+	To          Status    `json:"to"`
+	Reason      string    `json:"reason,omitempty"`
+	ModeratorID string    `json:"moderator_id"`
+	At          time.Time `json:"at"`
+}
+
+// Validate checks a requested action; hiding or removing needs a reason.
+func (a ModerationAction) Validate() error {
+	var fields []apperr.FieldError
+	if _, ok := ParseStatus(string(a.To)); !ok || a.To == StatusPending {
+		fields = append(fields, apperr.FieldError{Field: "status", Message: "must be published, hidden or removed"})
+	}
+	if (a.To == StatusHidden || a.To == StatusRemoved) && a.Reason == "" {
+		fields = append(fields, apperr.FieldError{Field: "reason", Message: "is required when hiding or removing"})
+	}
+	if len(a.Reason) > 500 {
+		fields = append(fields, apperr.FieldError{Field: "reason", Message: "must be at most 500 characters"})
+	}
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Photo is an image attached to a review. Blobs live in the blob store under
+// Key and ThumbKey; URL and ThumbURL are signed when a review is read.
+type Photo struct {
+	ID          string    `json:"id"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int       `json:"size"`
+	Key         string    `json:"-"`
+	ThumbKey    string    `json:"-"`
+	URL         string    `json:"url,omitempty"`
+	ThumbURL    string    `json:"thumb_url,omitempty"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
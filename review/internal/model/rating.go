@@ -0,0 +1,110 @@
+package model
+
+import (
+	"math"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// SubRatings are optional per-dimension ratings (1-5); zero means not rated.
+type SubRatings struct {
+	Food     int `json:"food,omitempty"`
+	Service  int `json:"service,omitempty"`
+	Ambience int `json:"ambience,omitempty"`
+	Value    int `json:"value,omitempty"`
+}
+
+// Dimensions returns the rated dimensions by name.
+func (s SubRatings) Dimensions() map[string]int {
+	out := make(map[string]int, 4)
+	for name, v := range map[string]int{"food": s.Food, "service": s.Service, "ambience": s.Ambience, "value": s.Value} {
+		if v != 0 {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+// Mean is the rounded mean of the rated dimensions, or 0 if none are rated.
+func (s SubRatings) Mean() int {
+	d := s.Dimensions()
+	if len(d) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, v := range d {
+		sum += v
+	}
+	return int(math.Round(float64(sum) / float64(len(d))))
+}
+
+func (s SubRatings) fieldErrors() []apperr.FieldError {
+	var fields []apperr.FieldError
+	for _, f := range []struct {
+		name string
+		v    int
+	}{{"food", s.Food}, {"service", s.Service}, {"ambience", s.Ambience}, {"value", s.Value}} {
+		if f.v != 0 && (f.v < 1 || f.v > 5) {
+			fields = append(fields, apperr.FieldError{Field: "sub_ratings." + f.name, Message: "must be between 1 and 5"})
+		}
+	}
+	return fields
+}
+
+// RatingStats aggregates one rating dimension. Histogram[i] counts ratings
+// of i+1 stars.
+type RatingStats struct {
+	Count     int     `json:"count"`
+	Average   float64 `json:"average"`
+	Histogram [5]int  `json:"histogram"`
+}
+
+// Add counts one rating (1-5).
+func (s *RatingStats) Add(v int) {
+	if v < 1 || v > 5 {
+		return
+	}
+	s.Histogram[v-1]++
+	s.Count++
+}
+
+// finish computes the average, rounded to two decimals.
+func (s *RatingStats) finish() {
+	if s.Count == 0 {
+		return
+	}
+	sum := 0
+	for i, n := range s.Histogram {
+		sum += (i + 1) * n
+	}
+	s.Average = math.Round(float64(sum)/float64(s.Count)*100) / 100
+}
+
+// Summary aggregates the ratings of a restaurant's reviews.
+type Summary struct {
+	RestaurantID int                    `json:"restaurant_id"`
+	Overall      RatingStats            `json:"overall"`
+	Dimensions   map[string]RatingStats `json:"dimensions"`
+}
+
+// Summarize aggregates the overall rating and each sub-rating of reviews.
+func Summarize(restaurantID int, reviews []Review) Summary {
+	out := Summary{RestaurantID: restaurantID, Dimensions: make(map[string]RatingStats, 4)}
+	for _, r := range reviews {
+		out.Overall.Add(r.Rating)
+		if r.SubRatings == nil {
+			continue
+		}
+		for name, v := range r.SubRatings.Dimensions() {
+			st := out.Dimensions[name]
+			st.Add(v)
+			out.Dimensions[name] = st
+		}
+	}
+	out.Overall.finish()
+	for name, st := range out.Dimensions {
+		st.finish()
+		out.Dimensions[name] = st
+	}
+	return out
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+// Response is the restaurant owner's public reply to a review.
+type Response struct {
+	Body      string    `json:"body"`
+	AuthorID  string    `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r Response) Validate() error {
+	switch n := utf8.RuneCountInString(strings.TrimSpace(r.Body)); {
+	case n == 0:
+		return apperr.Field("body", "is required")
+	case n > 1000:
+		return apperr.Field("body", "must be at most 1000 characters")
+	}
+	return nil
+}
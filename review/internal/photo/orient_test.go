@@ -0,0 +1,72 @@
+package photo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withOrientation inserts an EXIF APP1 segment carrying orientation o
+// (big-endian TIFF) right after the SOI marker of a JPEG.
+func withOrientation(t *testing.T, jpg []byte, o byte) []byte {
+	t.Helper()
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header, IFD0 at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, o, 0, 0, // Orientation, SHORT, count 1
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	n := len(payload) + 2
+	app1 := append([]byte{0xFF, 0xE1, byte(n >> 8), byte(n)}, payload...)
+	return append(append([]byte{0xFF, 0xD8}, app1...), jpg[2:]...)
+}
+
+// A landscape-stored portrait photo (orientation 6) comes out upright: the
+// dimensions swap and the left column of the stored pixels becomes the top
+// row.
+func TestProcessAppliesEXIFOrientation(t *testing.T) {
+	const w, h = 64, 32
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < w/4 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := withOrientation(t, buf.Bytes(), 6)
+	if o := exifOrientation(data); o != 6 {
+		t.Fatalf("exifOrientation = %d, want 6", o)
+	}
+
+	out, err := Process(bytes.NewReader(data), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Width != h || out.Height != w {
+		t.Fatalf("size %dx%d, want %dx%d", out.Width, out.Height, h, w)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(out.Full))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r < 0x4000
+	}
+	if !dark(h/2, 2) || dark(h/2, w-3) {
+		t.Fatal("stored left edge should be the top after rotating 90° clockwise")
+	}
+	if exifOrientation(out.Full) != 1 {
+		t.Fatal("re-encoded image still carries an orientation")
+	}
+}
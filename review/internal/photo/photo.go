@@ -0,0 +1,111 @@
+package photo
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+)
+
+const (
+	// Larger images are rejected before decoding (decompression bombs)
+	maxPixels = 40_000_000
+	thumbSize = 320
+)
+
+// Processed is an uploaded image re-encoded without metadata, plus its
+// thumbnail.
+type Processed struct {
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Full        []byte
+	Thumb       []byte
+}
+
+// Process validates and re-encodes an uploaded image. The type is sniffed
+// from the bytes, not taken from the client. Decoding and re-encoding drops
+// EXIF and any other embedded metadata (GPS location included), so a JPEG's
+// EXIF orientation is applied to the pixels first. JPEG and WebP come out as
+// JPEG, PNG stays PNG.
+func Process(r io.Reader, maxBytes int64) (Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return Processed{}, err
+	}
+	if int64(len(data)) > maxBytes {
+		return Processed{}, apperr.Field("photo", fmt.Sprintf("must be at most %d bytes", maxBytes))
+	}
+
+	sniffed := http.DetectContentType(data)
+	switch sniffed {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return Processed{}, apperr.Field("photo", "must be a JPEG, PNG or WebP image")
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, apperr.Field("photo", "is not a valid image")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return Processed{}, apperr.Field("photo", "dimensions are too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, apperr.Field("photo", "is not a valid image")
+	}
+
+	if sniffed == "image/jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	out := Processed{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	encode := encodeJPEG
+	out.ContentType, out.Ext = "image/jpeg", "jpg"
+	if sniffed == "image/png" {
+		encode = png.Encode
+		out.ContentType, out.Ext = "image/png", "png"
+	}
+
+	var full, thumb bytes.Buffer
+	if err := encode(&full, img); err != nil {
+		return Processed{}, err
+	}
+	if err := encode(&thumb, thumbnail(img, thumbSize)); err != nil {
+		return Processed{}, err
+	}
+	out.Full, out.Thumb = full.Bytes(), thumb.Bytes()
+	return out, nil
+}
+
+func encodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+// thumbnail scales img to fit in a size×size box, keeping the aspect ratio.
+// Smaller images are returned as-is.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
@@ -1,52 +1,124 @@
 package memory
 
 import (
-	"errors"
+	"maps"
+	"slices"
 	"sync"
 
+	"github.com/ChristopherLeo15/opentable/pkg/apperr"
+	"github.com/ChristopherLeo15/opentable/pkg/idgen"
 	m "github.com/ChristopherLeo15/opentable/review/internal/model"
 )
 
-var ErrNotFound = errors.New("review not found")
+var (
+	ErrNotFound = apperr.NotFound("review not found")
+	ErrConflict = apperr.Conflict("review id already exists")
+	// A reservation backs at most one review
+	ErrReservationReviewed = apperr.Conflict("reservation was already reviewed")
+)
 
 type Repo struct {
 	// Mutex for safe concurrent access
 	mu   sync.RWMutex
 	data []m.Review
+	seq  idgen.Sequence
+
+	// Indexes hold positions in data; appends keep them in insertion order
+	byID         map[int]int
+	byRestaurant map[int][]int
+	// Unique: reservation ID -> position of the review it backs
+	byReservation map[int]int
 }
 
 func New() *Repo {
-	return &Repo{data: make([]m.Review, 0, 32)}
+	return &Repo{
+		data:          make([]m.Review, 0, 32),
+		byID:          make(map[int]int, 32),
+		byRestaurant:  make(map[int][]int),
+		byReservation: make(map[int]int),
+	}
 }
 
-func (r *Repo) nextID() int {
-	max := 0
-	for _, v := range r.data {
-		if v.ID > max {
-			max = v.ID
+// Create stores x, assigning the next ID when x.ID is zero. A caller-supplied
+// ID that is already taken yields ErrConflict, a reservation that already
+// backs a review ErrReservationReviewed.
+func (r *Repo) Create(x m.Review) (m.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byReservation[x.ReservationID]; ok && x.ReservationID != 0 {
+		return m.Review{}, ErrReservationReviewed
+	}
+	if x.ID == 0 {
+		x.ID = r.seq.Next()
+	} else {
+		if _, ok := r.byID[x.ID]; ok {
+			return m.Review{}, ErrConflict
 		}
+		r.seq.Observe(x.ID)
 	}
-	return max + 1
+	i := len(r.data)
+	r.data = append(r.data, x)
+	r.byID[x.ID] = i
+	r.byRestaurant[x.RestaurantID] = append(r.byRestaurant[x.RestaurantID], i)
+	if x.ReservationID != 0 {
+		r.byReservation[x.ReservationID] = i
+	}
+	return x, nil
 }
 
-func (r *Repo) Create(x m.Review) m.Review {
+// Update applies fn to the stored review with the given id under the write
+// lock; if fn fails nothing changes. The ID, restaurant and reservation of a
+// review are fixed.
+func (r *Repo) Update(id int, fn func(*m.Review) error) (m.Review, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if x.ID == 0 {
-		x.ID = r.nextID()
+	i, ok := r.byID[id]
+	if !ok {
+		return m.Review{}, ErrNotFound
 	}
-	r.data = append(r.data, x)
-	return x
+	x := r.data[i]
+	x.Moderation = slices.Clone(x.Moderation)
+	x.Flags = slices.Clone(x.Flags)
+	x.Votes = maps.Clone(x.Votes)
+	x.Photos = slices.Clone(x.Photos)
+	if err := fn(&x); err != nil {
+		return m.Review{}, err
+	}
+	x.ID, x.RestaurantID, x.ReservationID = r.data[i].ID, r.data[i].RestaurantID, r.data[i].ReservationID
+	r.data[i] = x
+	return x, nil
+}
+
+func (r *Repo) GetByID(id int) (m.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if i, ok := r.byID[id]; ok {
+		return r.data[i], nil
+	}
+	return m.Review{}, ErrNotFound
+}
+
+func (r *Repo) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.data)
+}
+
+func (r *Repo) All() []m.Review {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]m.Review, len(r.data))
+	copy(out, r.data)
+	return out
 }
 
 func (r *Repo) ListByRestaurant(restaurantID int) []m.Review {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	out := make([]m.Review, 0, 8)
-	for _, v := range r.data {
-		if v.RestaurantID == restaurantID {
-			out = append(out, v)
-		}
+	positions := r.byRestaurant[restaurantID]
+	out := make([]m.Review, 0, len(positions))
+	for _, i := range positions {
+		out = append(out, r.data[i])
 	}
 	return out
 }
\ No newline at end of file
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	m "github.com/ChristopherLeo15/opentable/review/internal/model"
+)
+
+// Updates rewrite records in place: every index still points at the right
+// position, and the restaurant and reservation a review belongs to stay fixed.
+func TestUpdateKeepsIndexesConsistent(t *testing.T) {
+	r := New()
+	for i := 1; i <= 30; i++ {
+		if _, err := r.Create(m.Review{RestaurantID: i%3 + 1, ReservationID: 100 + i, Rating: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := 1; id <= 30; id += 2 {
+		_, err := r.Update(id, func(x *m.Review) error {
+			x.Rating = 5
+			x.Comment = fmt.Sprintf("edited %d", x.ID)
+			x.ID, x.RestaurantID, x.ReservationID = 999, 42, 7
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A failed update changes nothing
+	if _, err := r.Update(2, func(x *m.Review) error {
+		x.Rating = 1
+		return errors.New("rejected")
+	}); err == nil {
+		t.Fatal("update error was dropped")
+	}
+
+	all := r.All()
+	for _, x := range all {
+		got, err := r.GetByID(x.ID)
+		if err != nil || !reflect.DeepEqual(got, x) {
+			t.Fatalf("GetByID(%d) = %+v, %v; want %+v", x.ID, got, err, x)
+		}
+		if want := x.ID%3 + 1; x.RestaurantID != want {
+			t.Errorf("review %d moved to restaurant %d", x.ID, x.RestaurantID)
+		}
+		if x.ReservationID != 100+x.ID {
+			t.Errorf("review %d reservation changed to %d", x.ID, x.ReservationID)
+		}
+		if want := 3 + 2*(x.ID%2); x.Rating != want {
+			t.Errorf("review %d rating = %d, want %d", x.ID, x.Rating, want)
+		}
+	}
+	for rest := 1; rest <= 3; rest++ {
+		var want []m.Review
+		for _, x := range all {
+			if x.RestaurantID == rest {
+				want = append(want, x)
+			}
+		}
+		if got := r.ListByRestaurant(rest); !reflect.DeepEqual(got, want) {
+			t.Errorf("ListByRestaurant(%d) = %d reviews out of order or stale, want %d", rest, len(got), len(want))
+		}
+	}
+	if got := r.ListByRestaurant(42); len(got) != 0 {
+		t.Errorf("restaurant 42 has %d reviews after a rejected move", len(got))
+	}
+	if _, err := r.Create(m.Review{RestaurantID: 1, ReservationID: 101, Rating: 4}); !errors.Is(err, ErrReservationReviewed) {
+		t.Errorf("second review of reservation 101: %v", err)
+	}
+	if _, err := r.Create(m.Review{RestaurantID: 1, ReservationID: 7, Rating: 4}); err != nil {
+		t.Errorf("reservation 7 was never stored, got %v", err)
+	}
+}
+
+const (
+	benchRecords     = 100_000
+	benchRestaurants = 1_000
+)
+
+// benchRepo fills a store with n reviews spread over benchRestaurants and
+// returns it with a snapshot for the linear-scan baselines.
+func benchRepo(b *testing.B, n int) (*Repo, []m.Review) {
+	b.Helper()
+	r := New()
+	for i := 0; i < n; i++ {
+		if _, err := r.Create(m.Review{RestaurantID: i%benchRestaurants + 1, Rating: i%5 + 1, Comment: "fine"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return r, r.All()
+}
+
+// spread maps iteration i to an ID in [1, n], visiting the whole range
+// rather than only its start.
+func spread(i, n int) int {
+	return i*7919%n + 1
+}
+
+// The "scan" cases replay the lookups the store did before it was indexed.
+func BenchmarkGetByID(b *testing.B) {
+	r, all := benchRepo(b, benchRecords)
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.GetByID(spread(i, benchRecords)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			id := spread(i, benchRecords)
+			for _, x := range all {
+				if x.ID == id {
+					break
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkListByRestaurant(b *testing.B) {
+	r, all := benchRepo(b, benchRecords)
+	b.Run("index", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.ListByRestaurant(i%benchRestaurants + 1)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rest := i%benchRestaurants + 1
+			var out []m.Review
+			for _, x := range all {
+				if x.RestaurantID == rest {
+					out = append(out, x)
+				}
+			}
+		}
+	})
+}
+
+// Create into a store already holding benchRecords reviews, with a
+// caller-supplied ID so the duplicate check runs.
+func BenchmarkCreate(b *testing.B) {
+	b.Run("index", func(b *testing.B) {
+		r, _ := benchRepo(b, benchRecords)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := r.Create(m.Review{ID: benchRecords + i + 1, RestaurantID: i%benchRestaurants + 1, ReservationID: i + 1, Rating: 4}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		_, all := benchRepo(b, benchRecords)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			id := benchRecords + i + 1
+			for _, x := range all {
+				if x.ID == id || x.ReservationID == i+1 {
+					b.Fatal("duplicate")
+				}
+			}
+			all = append(all, m.Review{ID: id, RestaurantID: i%benchRestaurants + 1, ReservationID: i + 1, Rating: 4})
+		}
+	})
+}
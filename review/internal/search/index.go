@@ -0,0 +1,206 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Words too common to be worth indexing. They still take up a position,
+// so phrases like "view of the river" match exactly.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "we": true, "were": true, "will": true, "with": true,
+}
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+type token struct {
+	term string
+	pos  int
+}
+
+// tokenize splits text into stemmed terms with their word positions.
+func tokenize(text string) []token {
+	var out []token
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for pos, w := range words {
+		if stopwords[w] {
+			continue
+		}
+		out = append(out, token{term: Stem(w), pos: pos})
+	}
+	return out
+}
+
+type doc struct {
+	restaurantID int
+	length       int
+	terms        []string
+}
+
+// Index is an inverted index over review comments with positional postings
+// for phrase queries. It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[int]doc
+	postings map[string]map[int][]int // term -> review id -> positions
+	totalLen int
+}
+
+func NewIndex() *Index {
+	return &Index{docs: make(map[int]doc), postings: make(map[string]map[int][]int)}
+}
+
+// Put indexes (or reindexes) the comment of a review.
+func (ix *Index) Put(id, restaurantID int, text string) {
+	toks := tokenize(text)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+
+	d := doc{restaurantID: restaurantID, length: len(toks)}
+	for _, t := range toks {
+		p := ix.postings[t.term]
+		if p == nil {
+			p = make(map[int][]int)
+			ix.postings[t.term] = p
+		}
+		if len(p[id]) == 0 {
+			d.terms = append(d.terms, t.term)
+		}
+		p[id] = append(p[id], t.pos)
+	}
+	ix.docs[id] = d
+	ix.totalLen += d.length
+}
+
+// Remove drops a review from the index.
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id int) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range d.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLen -= d.length
+	delete(ix.docs, id)
+}
+
+// Hit is a matching review and its BM25 score.
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+// Search returns reviews matching every term and phrase of q, best first.
+// restaurantID narrows the search when positive.
+func (ix *Index) Search(q Query, restaurantID int) []Hit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if q.Empty() {
+		return nil
+	}
+
+	// Every term (including phrase terms) must appear; start from the
+	// rarest to keep the candidate set small.
+	all := q.allTerms()
+	sort.Slice(all, func(i, j int) bool { return len(ix.postings[all[i]]) < len(ix.postings[all[j]]) })
+	var candidates []int
+	for id := range ix.postings[all[0]] {
+		if restaurantID > 0 && ix.docs[id].restaurantID != restaurantID {
+			continue
+		}
+		candidates = append(candidates, id)
+	}
+
+	hits := make([]Hit, 0, len(candidates))
+	for _, id := range candidates {
+		if !ix.matches(id, all, q.Phrases) {
+			continue
+		}
+		hits = append(hits, Hit{ID: id, Score: ix.score(id, all)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+func (ix *Index) matches(id int, terms []string, phrases [][]token) bool {
+	for _, t := range terms {
+		if len(ix.postings[t][id]) == 0 {
+			return false
+		}
+	}
+	for _, ph := range phrases {
+		if !ix.hasPhrase(id, ph) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPhrase checks that the phrase terms occur at the same relative
+// positions as in the query.
+func (ix *Index) hasPhrase(id int, ph []token) bool {
+	first := ph[0]
+	for _, start := range ix.postings[first.term][id] {
+		ok := true
+		for _, t := range ph[1:] {
+			want := start + t.pos - first.pos
+			if !containsInt(ix.postings[t.term][id], want) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (ix *Index) score(id int, terms []string) float64 {
+	n := float64(len(ix.docs))
+	avg := float64(ix.totalLen) / math.Max(1, n)
+	dl := float64(ix.docs[id].length)
+	s := 0.0
+	for _, t := range terms {
+		df := float64(len(ix.postings[t]))
+		tf := float64(len(ix.postings[t][id]))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		s += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*dl/math.Max(1, avg)))
+	}
+	return s
+}
+
+func containsInt(sorted []int, v int) bool {
+	i := sort.SearchInts(sorted, v)
+	return i < len(sorted) && sorted[i] == v
+}
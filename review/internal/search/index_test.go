@@ -0,0 +1,241 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []token
+	}{
+		{"", nil},
+		{"Pasta", []token{{"pasta", 0}}},
+		// Stopwords are dropped but keep their position
+		{"The view of the river", []token{{"view", 1}, {"river", 4}}},
+		{"Great   food!!! Friendly,staff", []token{{"great", 0}, {"food", 1}, {"friendli", 2}, {"staff", 3}}},
+		{"Table 12 at 8pm", []token{{"tabl", 0}, {"12", 1}, {"8pm", 3}}},
+		{"Dined twice, dining again", []token{{"dine", 0}, {"twice", 1}, {"dine", 2}, {"again", 3}}},
+		{"Crème brûlée", []token{{"crème", 0}, {"brûlée", 1}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			if got := tokenize(tc.text); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("tokenize(%q) = %v, want %v", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		q    string
+		want Query
+	}{
+		{"", Query{}},
+		{"the of", Query{}},
+		{"Dining view", Query{Terms: []string{"dine", "view"}}},
+		{`"view of the river"`, Query{Phrases: [][]token{{{"view", 0}, {"river", 3}}}}},
+		// A one-word phrase is just a term
+		{`"pasta" wine`, Query{Terms: []string{"pasta", "wine"}}},
+		{`cosy "river view`, Query{Terms: []string{"cosi"}, Phrases: [][]token{{{"river", 0}, {"view", 1}}}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.q, func(t *testing.T) {
+			if got := ParseQuery(tc.q); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("ParseQuery(%q) = %+v, want %+v", tc.q, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSearchPhrases(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(1, 1, "A table with a view of the river")
+	ix.Put(2, 1, "River view, and a view of a garden")
+	ix.Put(3, 1, "We viewed the river from the terrace")
+
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		{"view river", []int{1, 2, 3}},
+		{`"view of the river"`, []int{1}},
+		// Stopwords only hold their place; which ones doesn't matter
+		{`"view of a river"`, []int{1}},
+		{`"river view"`, []int{2}},
+		{`"viewed the river"`, []int{3}},
+		{`"river from the terrace" view`, []int{3}},
+		{`"garden view"`, nil},
+		{"view sushi", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.q, func(t *testing.T) {
+			if got := ids(ix.Search(ParseQuery(tc.q), 0)); !sameIDs(got, tc.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tc.q, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name string
+		docs []string // review i+1 at restaurant 1
+		q    string
+		want []int
+	}{
+		{
+			name: "term frequency",
+			docs: []string{"pasta salad bread wine", "pasta pasta bread wine"},
+			q:    "pasta",
+			want: []int{2, 1},
+		},
+		{
+			name: "shorter review first",
+			docs: []string{"pasta with a long story about parking and waiting staff", "pasta fine"},
+			q:    "pasta",
+			want: []int{2, 1},
+		},
+		{
+			name: "rare terms weigh more",
+			docs: []string{"pasta pasta tiramisu", "pasta tiramisu tiramisu", "pasta", "pasta"},
+			q:    "pasta tiramisu",
+			want: []int{2, 1},
+		},
+		{
+			name: "ties by id",
+			docs: []string{"pasta", "pasta", "pasta"},
+			q:    "pasta",
+			want: []int{1, 2, 3},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ix := NewIndex()
+			for i, d := range tc.docs {
+				ix.Put(i+1, 1, d)
+			}
+			if got := ids(ix.Search(ParseQuery(tc.q), 0)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tc.q, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("score", func(t *testing.T) {
+		// One review of average length: tf=1 cancels out and the score is
+		// the idf, ln(1 + (1-1+0.5)/(1+0.5))
+		ix := NewIndex()
+		ix.Put(1, 1, "pasta")
+		hits := ix.Search(ParseQuery("pasta"), 0)
+		if want := math.Log(4.0 / 3); len(hits) != 1 || math.Abs(hits[0].Score-want) > 1e-12 {
+			t.Fatalf("hits = %+v, want score %v", hits, want)
+		}
+	})
+
+	t.Run("restaurant filter", func(t *testing.T) {
+		ix := NewIndex()
+		ix.Put(1, 1, "pasta")
+		ix.Put(2, 2, "pasta")
+		if got := ids(ix.Search(ParseQuery("pasta"), 2)); !reflect.DeepEqual(got, []int{2}) {
+			t.Fatalf("Search at restaurant 2 = %v, want [2]", got)
+		}
+	})
+}
+
+// Edits and deletes must leave the index exactly as if it had been built
+// from the final reviews: same hits, same scores, no stale postings.
+func TestIncrementalUpdates(t *testing.T) {
+	type op struct {
+		id     int
+		text   string
+		remove bool
+	}
+	tests := []struct {
+		name  string
+		ops   []op
+		final map[int]string
+	}{
+		{
+			name:  "edit replaces terms",
+			ops:   []op{{id: 1, text: "cold soup"}, {id: 2, text: "warm soup"}, {id: 1, text: "hot pasta"}},
+			final: map[int]string{1: "hot pasta", 2: "warm soup"},
+		},
+		{
+			name:  "edit changes length",
+			ops:   []op{{id: 1, text: "soup"}, {id: 2, text: "soup soup bread"}, {id: 2, text: "soup and a very long story about bread"}},
+			final: map[int]string{1: "soup", 2: "soup and a very long story about bread"},
+		},
+		{
+			name:  "delete",
+			ops:   []op{{id: 1, text: "soup"}, {id: 2, text: "soup bread"}, {id: 1, remove: true}},
+			final: map[int]string{2: "soup bread"},
+		},
+		{
+			name:  "delete twice and unknown ids",
+			ops:   []op{{id: 1, text: "soup"}, {id: 1, remove: true}, {id: 1, remove: true}, {id: 9, remove: true}},
+			final: map[int]string{},
+		},
+		{
+			name:  "re-add after delete",
+			ops:   []op{{id: 1, text: "soup"}, {id: 1, remove: true}, {id: 1, text: "bread soup"}},
+			final: map[int]string{1: "bread soup"},
+		},
+	}
+	queries := []string{"soup", "bread", "pasta", "cold", "soup bread", `"hot pasta"`, "story"}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ix := NewIndex()
+			for _, o := range tc.ops {
+				if o.remove {
+					ix.Remove(o.id)
+				} else {
+					ix.Put(o.id, 1, o.text)
+				}
+			}
+			fresh := NewIndex()
+			for id, text := range tc.final {
+				fresh.Put(id, 1, text)
+			}
+
+			for _, q := range queries {
+				if got, want := ix.Search(ParseQuery(q), 0), fresh.Search(ParseQuery(q), 0); !reflect.DeepEqual(got, want) {
+					t.Errorf("Search(%q) = %+v, want %+v", q, got, want)
+				}
+			}
+			if len(ix.postings) != len(fresh.postings) || ix.totalLen != fresh.totalLen || len(ix.docs) != len(fresh.docs) {
+				t.Fatalf("index holds %d terms, %d docs, length %d; want %d, %d, %d",
+					len(ix.postings), len(ix.docs), ix.totalLen, len(fresh.postings), len(fresh.docs), fresh.totalLen)
+			}
+		})
+	}
+}
+
+// ----- Support function -----
+
+func ids(hits []Hit) []int {
+	var out []int
+	for _, h := range hits {
+		out = append(out, h.ID)
+	}
+	return out
+}
+
+// sameIDs compares hit ids ignoring order.
+func sameIDs(got, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[int]bool, len(got))
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
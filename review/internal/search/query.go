@@ -0,0 +1,54 @@
+package search
+
+import "strings"
+
+// Query is a parsed search string: bare words must all appear, and
+// "quoted phrases" must appear as written.
+type Query struct {
+	Terms   []string
+	Phrases [][]token
+}
+
+// ParseQuery parses q. Stopwords are dropped from bare words; inside
+// phrases they only hold their position.
+func ParseQuery(q string) Query {
+	var out Query
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		toks := tokenize(part)
+		if i%2 == 1 && len(toks) > 1 {
+			out.Phrases = append(out.Phrases, toks)
+			continue
+		}
+		for _, t := range toks {
+			out.Terms = append(out.Terms, t.term)
+		}
+	}
+	return out
+}
+
+// Empty reports whether q has nothing to search for.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0
+}
+
+// allTerms returns every distinct term of q, phrases included.
+func (q Query) allTerms() []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	for _, t := range q.Terms {
+		add(t)
+	}
+	for _, ph := range q.Phrases {
+		for _, t := range ph {
+			add(t.term)
+		}
+	}
+	return out
+}
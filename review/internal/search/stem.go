@@ -0,0 +1,205 @@
+package search
+
+// Stem reduces an English word to its stem with the Porter algorithm, so
+// "dining", "dined" and "dines" all index as "dine". Words must be
+// lowercase ASCII; anything else is returned unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	w := []byte(word)
+	w = step1a(w)
+	w = step1b(w)
+	w = step1c(w)
+	w = replaceLongest(w, step2, 0)
+	w = replaceLongest(w, step3, 0)
+	w = step4(w)
+	w = step5(w)
+	return string(w)
+}
+
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the VC sequences in w ([C](VC)^m[V]).
+func measure(w []byte) int {
+	n, i := 0, 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		n++
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+	}
+	return n
+}
+
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports consonant-vowel-consonant where the last is not w, x or y.
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	c := w[n-1]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func hasSuffix(w []byte, s string) bool {
+	return len(w) >= len(s) && string(w[len(w)-len(s):]) == s
+}
+
+func replace(w []byte, suffix, with string) []byte {
+	return append(w[:len(w)-len(suffix)], with...)
+}
+
+func step1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"):
+		return replace(w, "sses", "ss")
+	case hasSuffix(w, "ies"):
+		return replace(w, "ies", "i")
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return replace(w, "s", "")
+	}
+	return w
+}
+
+func step1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return replace(w, "eed", "ee")
+		}
+		return w
+	}
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem, 'e')
+	case endsDoubleConsonant(stem):
+		if c := stem[len(stem)-1]; c != 'l' && c != 's' && c != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem, 'e')
+	}
+	return stem
+}
+
+func step1c(w []byte) []byte {
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		w[len(w)-1] = 'i'
+	}
+	return w
+}
+
+var step2 = map[string]string{
+	"ational": "ate", "tional": "tion", "enci": "ence", "anci": "ance",
+	"izer": "ize", "bli": "ble", "alli": "al", "entli": "ent", "eli": "e",
+	"ousli": "ous", "ization": "ize", "ation": "ate", "ator": "ate",
+	"alism": "al", "iveness": "ive", "fulness": "ful", "ousness": "ous",
+	"aliti": "al", "iviti": "ive", "biliti": "ble", "logi": "log",
+}
+
+var step3 = map[string]string{
+	"icate": "ic", "ative": "", "alize": "al", "iciti": "ic",
+	"ical": "ic", "ful": "", "ness": "",
+}
+
+var step4Suffixes = map[string]string{
+	"al": "", "ance": "", "ence": "", "er": "", "ic": "", "able": "",
+	"ible": "", "ant": "", "ement": "", "ment": "", "ent": "", "ion": "",
+	"ou": "", "ism": "", "ate": "", "iti": "", "ous": "", "ive": "", "ize": "",
+}
+
+// longest returns the longest suffix of w found in rules.
+func longest(w []byte, rules map[string]string) (string, bool) {
+	best, found := "", false
+	for s := range rules {
+		if len(s) > len(best) && hasSuffix(w, s) {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// replaceLongest applies the rule for the longest matching suffix if the
+// remaining stem has measure > minMeasure. Only one rule is ever tried.
+func replaceLongest(w []byte, rules map[string]string, minMeasure int) []byte {
+	s, ok := longest(w, rules)
+	if !ok || measure(w[:len(w)-len(s)]) <= minMeasure {
+		return w
+	}
+	return replace(w, s, rules[s])
+}
+
+func step4(w []byte) []byte {
+	s, ok := longest(w, step4Suffixes)
+	if !ok {
+		return w
+	}
+	stem := w[:len(w)-len(s)]
+	if s == "ion" && (len(stem) == 0 || (stem[len(stem)-1] != 's' && stem[len(stem)-1] != 't')) {
+		return w
+	}
+	if measure(stem) > 1 {
+		return stem
+	}
+	return w
+}
+
+func step5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		if m := measure(stem); m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && w[len(w)-1] == 'l' {
+		w = w[:len(w)-1]
+	}
+	return w
+}
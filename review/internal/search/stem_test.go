@@ -0,0 +1,63 @@
+package search
+
+import "testing"
+
+// Expected stems come from the reference vocabulary of Porter's algorithm.
+func TestStem(t *testing.T) {
+	tests := []struct{ word, want string }{
+		// Step 1a: plurals
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "ti"},
+		{"caress", "caress"},
+		{"cats", "cat"},
+		// Step 1b: -eed, -ed, -ing
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"bled", "bled"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"conflated", "conflat"},
+		{"hopping", "hop"},
+		{"tanned", "tan"},
+		{"falling", "fall"},
+		{"hissing", "hiss"},
+		{"fizzed", "fizz"},
+		{"failing", "fail"},
+		{"filing", "file"},
+		// Step 1c: y -> i
+		{"happy", "happi"},
+		{"sky", "sky"},
+		// Steps 2-4: derivational suffixes
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"hopeful", "hope"},
+		{"goodness", "good"},
+		{"electrical", "electr"},
+		{"adjustment", "adjust"},
+		{"adoption", "adopt"},
+		// Step 5: final e and ll
+		{"probate", "probat"},
+		{"rate", "rate"},
+		{"cease", "ceas"},
+		{"controll", "control"},
+		{"roll", "roll"},
+		// Word forms a review search should conflate
+		{"dining", "dine"},
+		{"dined", "dine"},
+		{"dines", "dine"},
+		{"delicious", "delici"},
+		// Left alone: short, non-ASCII, digits
+		{"is", "is"},
+		{"crème", "crème"},
+		{"b52s", "b52s"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.word, func(t *testing.T) {
+			if got := Stem(tc.word); got != tc.want {
+				t.Fatalf("Stem(%q) = %q, want %q", tc.word, got, tc.want)
+			}
+		})
+	}
+}